@@ -0,0 +1,71 @@
+package consistenthash
+
+import (
+	"hash/crc32"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+)
+
+const defaultReplicas = 160
+
+var _ balancer.Builder = (*Builder)(nil)
+
+// Builder 一致性哈希，按 balancer.ContextWithHashKey 设置的 key 选择节点，
+// 相同的 key 总是落到同一个节点上，节点变化时只有少部分 key 会迁移。
+// 没有设置 key 的调用随机选择节点。
+type Builder struct {
+	// 每个节点在哈希环上的虚拟节点数，默认 160
+	Replicas int
+}
+
+func (b *Builder) Build(nodes []balancer.Node) balancer.Picker {
+	replicas := b.Replicas
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+
+	p := &Picker{
+		nodes: nodes,
+		ring:  make([]uint32, 0, len(nodes)*replicas),
+		owner: make(map[uint32]balancer.Node, len(nodes)*replicas),
+	}
+	for _, node := range nodes {
+		for i := 0; i < replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(node.Addr + "#" + strconv.Itoa(i)))
+			p.ring = append(p.ring, hash)
+			p.owner[hash] = node
+		}
+	}
+	slices.Sort(p.ring)
+	return p
+}
+
+var _ balancer.Picker = (*Picker)(nil)
+
+type Picker struct {
+	nodes []balancer.Node
+	ring  []uint32
+	owner map[uint32]balancer.Node
+}
+
+func (p *Picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	if len(p.nodes) == 0 {
+		return balancer.PickResult{}, balancer.ErrNoAvailableNode
+	}
+
+	key, ok := balancer.HashKeyFromContext(info.Ctx)
+	if !ok {
+		return balancer.PickResult{Node: p.nodes[rand.IntN(len(p.nodes))]}, nil
+	}
+
+	// 顺时针找到第一个虚拟节点
+	hash := crc32.ChecksumIEEE([]byte(key))
+	index, _ := slices.BinarySearch(p.ring, hash)
+	if index == len(p.ring) {
+		index = 0
+	}
+	return balancer.PickResult{Node: p.owner[p.ring[index]]}, nil
+}
@@ -0,0 +1,55 @@
+package consistenthash
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pickAll(t *testing.T, p balancer.Picker, keys int) map[string]string {
+	res := make(map[string]string, keys)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		picked, err := p.Pick(balancer.PickInfo{Ctx: balancer.ContextWithHashKey(context.Background(), key)})
+		require.NoError(t, err)
+		res[key] = picked.Node.Addr
+	}
+	return res
+}
+
+func TestPicker(t *testing.T) {
+	nodes := []balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}}
+	before := pickAll(t, (&Builder{}).Build(nodes), 1000)
+
+	// 相同的 key 总是落到同一个节点
+	assert.Equal(t, before, pickAll(t, (&Builder{}).Build(nodes), 1000))
+
+	// 新增节点后，原有节点之间不会发生迁移
+	after := pickAll(t, (&Builder{}).Build(append(nodes, balancer.Node{Addr: "node-4"})), 1000)
+	moved := 0
+	for key, addr := range after {
+		if addr != before[key] {
+			assert.Equal(t, "node-4", addr)
+			moved++
+		}
+	}
+	assert.Less(t, moved, 500)
+
+	_, err := (&Builder{}).Build(nil).Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.ErrorIs(t, err, balancer.ErrNoAvailableNode)
+}
+
+func BenchmarkPicker(b *testing.B) {
+	p := (&Builder{}).Build([]balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}})
+	info := balancer.PickInfo{Ctx: balancer.ContextWithHashKey(context.Background(), "key")}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = p.Pick(info)
+		}
+	})
+}
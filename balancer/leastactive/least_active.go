@@ -0,0 +1,58 @@
+package leastactive
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+)
+
+var _ balancer.Builder = (*Builder)(nil)
+
+// Builder 最少活跃请求数，选择当前进行中请求最少的节点。
+type Builder struct{}
+
+func (b *Builder) Build(nodes []balancer.Node) balancer.Picker {
+	activeNodes := make([]*activeNode, 0, len(nodes))
+	for _, node := range nodes {
+		activeNodes = append(activeNodes, &activeNode{node: node})
+	}
+	return &Picker{nodes: activeNodes}
+}
+
+type activeNode struct {
+	node   balancer.Node
+	active atomic.Int64
+}
+
+var _ balancer.Picker = (*Picker)(nil)
+
+type Picker struct {
+	nodes []*activeNode
+}
+
+func (p *Picker) Pick(_ balancer.PickInfo) (balancer.PickResult, error) {
+	if len(p.nodes) == 0 {
+		return balancer.PickResult{}, balancer.ErrNoAvailableNode
+	}
+
+	// 从随机位置开始遍历，避免活跃数相同时总是选中同一个节点
+	offset := rand.IntN(len(p.nodes))
+	var selected *activeNode
+	var least int64
+	for i := range p.nodes {
+		n := p.nodes[(offset+i)%len(p.nodes)]
+		active := n.active.Load()
+		if selected == nil || active < least {
+			selected, least = n, active
+		}
+	}
+
+	selected.active.Add(1)
+	return balancer.PickResult{
+		Node: selected.node,
+		Done: func(_ balancer.DoneInfo) {
+			selected.active.Add(-1)
+		},
+	}, nil
+}
@@ -0,0 +1,48 @@
+package leastactive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPicker(t *testing.T) {
+	nodes := []balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}}
+	p := (&Builder{}).Build(nodes)
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	// 三个请求都未结束时，分别落在三个节点上
+	results := make([]balancer.PickResult, 0, 3)
+	picked := make(map[string]struct{}, 3)
+	for i := 0; i < 3; i++ {
+		res, err := p.Pick(info)
+		require.NoError(t, err)
+		results = append(results, res)
+		picked[res.Node.Addr] = struct{}{}
+	}
+	assert.Len(t, picked, 3)
+
+	// 只有一个节点的请求结束，下一个请求一定选择该节点
+	results[1].Done(balancer.DoneInfo{})
+	res, err := p.Pick(info)
+	require.NoError(t, err)
+	assert.Equal(t, results[1].Node.Addr, res.Node.Addr)
+
+	_, err = (&Builder{}).Build(nil).Pick(info)
+	assert.ErrorIs(t, err, balancer.ErrNoAvailableNode)
+}
+
+func BenchmarkPicker(b *testing.B) {
+	p := (&Builder{}).Build([]balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}})
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			res, _ := p.Pick(info)
+			res.Done(balancer.DoneInfo{})
+		}
+	})
+}
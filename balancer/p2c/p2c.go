@@ -0,0 +1,98 @@
+package p2c
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+)
+
+// decayTime EWMA 的衰减时间，距离上次更新越久，旧的延迟占比越小
+const decayTime = 10 * time.Second
+
+var _ balancer.Builder = (*Builder)(nil)
+
+// Builder power of two choices，随机选择两个节点，再从中选择负载较低的一个。
+//
+// 节点负载为 EWMA 延迟 * (进行中的请求数 + 1)，既考虑了节点的处理速度，也考虑了节点当前的排队情况。
+type Builder struct{}
+
+func (b *Builder) Build(nodes []balancer.Node) balancer.Picker {
+	ewmaNodes := make([]*ewmaNode, 0, len(nodes))
+	for _, node := range nodes {
+		ewmaNodes = append(ewmaNodes, &ewmaNode{node: node})
+	}
+	return &Picker{nodes: ewmaNodes, now: time.Now}
+}
+
+type ewmaNode struct {
+	node balancer.Node
+
+	mu       sync.Mutex
+	latency  float64 // EWMA 延迟，单位为纳秒
+	inflight int64
+	last     time.Time
+}
+
+func (n *ewmaNode) load() float64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.latency * float64(n.inflight+1)
+}
+
+func (n *ewmaNode) observe(now time.Time, latency time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.inflight--
+	if n.last.IsZero() {
+		n.latency = float64(latency)
+	} else {
+		w := math.Exp(-float64(now.Sub(n.last)) / float64(decayTime))
+		n.latency = n.latency*w + float64(latency)*(1-w)
+	}
+	n.last = now
+}
+
+var _ balancer.Picker = (*Picker)(nil)
+
+type Picker struct {
+	nodes []*ewmaNode
+
+	now func() time.Time
+}
+
+func (p *Picker) Pick(_ balancer.PickInfo) (balancer.PickResult, error) {
+	var selected *ewmaNode
+	switch len(p.nodes) {
+	case 0:
+		return balancer.PickResult{}, balancer.ErrNoAvailableNode
+	case 1:
+		selected = p.nodes[0]
+	default:
+		i := rand.IntN(len(p.nodes))
+		j := rand.IntN(len(p.nodes) - 1)
+		if j >= i {
+			j++
+		}
+		selected = p.nodes[i]
+		if p.nodes[j].load() < selected.load() {
+			selected = p.nodes[j]
+		}
+	}
+
+	selected.mu.Lock()
+	selected.inflight++
+	selected.mu.Unlock()
+
+	start := p.now()
+	return balancer.PickResult{
+		Node: selected.node,
+		Done: func(_ balancer.DoneInfo) {
+			now := p.now()
+			selected.observe(now, now.Sub(start))
+		},
+	}, nil
+}
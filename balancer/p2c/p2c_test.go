@@ -0,0 +1,51 @@
+package p2c
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPicker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := (&Builder{}).Build([]balancer.Node{{Addr: "fast"}, {Addr: "slow"}}).(*Picker)
+	p.now = func() time.Time { return now }
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	// 预热，让两个节点都有延迟数据
+	latencies := map[string]time.Duration{"fast": time.Millisecond, "slow": 100 * time.Millisecond}
+	for i := 0; i < 10; i++ {
+		res, err := p.Pick(info)
+		require.NoError(t, err)
+		now = now.Add(latencies[res.Node.Addr])
+		res.Done(balancer.DoneInfo{})
+	}
+
+	// 只有两个节点时每次都会比较两个节点，总是选择延迟低的节点
+	for i := 0; i < 10; i++ {
+		res, err := p.Pick(info)
+		require.NoError(t, err)
+		assert.Equal(t, "fast", res.Node.Addr)
+		now = now.Add(latencies[res.Node.Addr])
+		res.Done(balancer.DoneInfo{})
+	}
+
+	_, err := (&Builder{}).Build(nil).Pick(info)
+	assert.ErrorIs(t, err, balancer.ErrNoAvailableNode)
+}
+
+func BenchmarkPicker(b *testing.B) {
+	p := (&Builder{}).Build([]balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}})
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			res, _ := p.Pick(info)
+			res.Done(balancer.DoneInfo{})
+		}
+	})
+}
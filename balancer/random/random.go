@@ -0,0 +1,29 @@
+package random
+
+import (
+	"math/rand/v2"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+)
+
+var _ balancer.Builder = (*Builder)(nil)
+
+// Builder 随机
+type Builder struct{}
+
+func (b *Builder) Build(nodes []balancer.Node) balancer.Picker {
+	return &Picker{nodes: nodes}
+}
+
+var _ balancer.Picker = (*Picker)(nil)
+
+type Picker struct {
+	nodes []balancer.Node
+}
+
+func (p *Picker) Pick(_ balancer.PickInfo) (balancer.PickResult, error) {
+	if len(p.nodes) == 0 {
+		return balancer.PickResult{}, balancer.ErrNoAvailableNode
+	}
+	return balancer.PickResult{Node: p.nodes[rand.IntN(len(p.nodes))]}, nil
+}
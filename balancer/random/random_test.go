@@ -0,0 +1,39 @@
+package random
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPicker(t *testing.T) {
+	nodes := []balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}}
+	p := (&Builder{}).Build(nodes)
+
+	counts := make(map[string]int, len(nodes))
+	for i := 0; i < 3000; i++ {
+		res, err := p.Pick(balancer.PickInfo{Ctx: context.Background()})
+		require.NoError(t, err)
+		counts[res.Node.Addr]++
+	}
+	for _, node := range nodes {
+		assert.InDelta(t, 1000, counts[node.Addr], 200)
+	}
+
+	_, err := (&Builder{}).Build(nil).Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.ErrorIs(t, err, balancer.ErrNoAvailableNode)
+}
+
+func BenchmarkPicker(b *testing.B) {
+	p := (&Builder{}).Build([]balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}})
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = p.Pick(info)
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package roundrobin
+
+import (
+	"sync/atomic"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+)
+
+var _ balancer.Builder = (*Builder)(nil)
+
+// Builder 轮询
+type Builder struct{}
+
+func (b *Builder) Build(nodes []balancer.Node) balancer.Picker {
+	return &Picker{nodes: nodes}
+}
+
+var _ balancer.Picker = (*Picker)(nil)
+
+type Picker struct {
+	nodes []balancer.Node
+	next  atomic.Uint64
+}
+
+func (p *Picker) Pick(_ balancer.PickInfo) (balancer.PickResult, error) {
+	if len(p.nodes) == 0 {
+		return balancer.PickResult{}, balancer.ErrNoAvailableNode
+	}
+
+	index := (p.next.Add(1) - 1) % uint64(len(p.nodes))
+	return balancer.PickResult{Node: p.nodes[index]}, nil
+}
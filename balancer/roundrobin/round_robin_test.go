@@ -0,0 +1,37 @@
+package roundrobin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPicker(t *testing.T) {
+	nodes := []balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}}
+	p := (&Builder{}).Build(nodes)
+
+	picked := make([]string, 0, 6)
+	for i := 0; i < 6; i++ {
+		res, err := p.Pick(balancer.PickInfo{Ctx: context.Background()})
+		require.NoError(t, err)
+		picked = append(picked, res.Node.Addr)
+	}
+	assert.Equal(t, []string{"node-1", "node-2", "node-3", "node-1", "node-2", "node-3"}, picked)
+
+	_, err := (&Builder{}).Build(nil).Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.ErrorIs(t, err, balancer.ErrNoAvailableNode)
+}
+
+func BenchmarkPicker(b *testing.B) {
+	p := (&Builder{}).Build([]balancer.Node{{Addr: "node-1"}, {Addr: "node-2"}, {Addr: "node-3"}})
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = p.Pick(info)
+		}
+	})
+}
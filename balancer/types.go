@@ -0,0 +1,58 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNoAvailableNode = errors.New("[easy-rpc] no available node")
+
+// Node 可供选择的后端节点
+type Node struct {
+	Addr string
+	// 权重，只有加权类的负载均衡算法才会使用
+	Weight uint32
+}
+
+// PickInfo 选择节点时的调用信息
+type PickInfo struct {
+	Ctx     context.Context
+	Service string
+	Method  string
+}
+
+// DoneInfo 调用结束后的结果信息
+type DoneInfo struct {
+	Err     error
+	Latency time.Duration
+}
+
+type PickResult struct {
+	Node Node
+	// Done 调用结束后回调，不需要统计调用结果的 Picker 可以不设置
+	Done func(info DoneInfo)
+}
+
+// Picker 为每次调用选择一个节点，需要并发安全。
+type Picker interface {
+	Pick(info PickInfo) (PickResult, error)
+}
+
+// Builder 根据节点列表构建 Picker，节点列表变化时会重新构建。
+type Builder interface {
+	Build(nodes []Node) Picker
+}
+
+type contextKeyHashKey struct{}
+
+// ContextWithHashKey 设置一致性哈希使用的 key。
+func ContextWithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKeyHashKey{}, key)
+}
+
+// HashKeyFromContext 获取一致性哈希使用的 key。
+func HashKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(contextKeyHashKey{}).(string)
+	return key, ok
+}
@@ -0,0 +1,61 @@
+package weighted
+
+import (
+	"sync"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+)
+
+var _ balancer.Builder = (*Builder)(nil)
+
+// Builder 平滑加权轮询，权重为 0 的节点按权重 1 处理。
+type Builder struct{}
+
+func (b *Builder) Build(nodes []balancer.Node) balancer.Picker {
+	weightedNodes := make([]*weightedNode, 0, len(nodes))
+	for _, node := range nodes {
+		weight := int64(node.Weight)
+		if weight == 0 {
+			weight = 1
+		}
+		weightedNodes = append(weightedNodes, &weightedNode{node: node, weight: weight})
+	}
+	return &Picker{nodes: weightedNodes}
+}
+
+type weightedNode struct {
+	node          balancer.Node
+	weight        int64
+	currentWeight int64
+}
+
+var _ balancer.Picker = (*Picker)(nil)
+
+type Picker struct {
+	mu    sync.Mutex
+	nodes []*weightedNode
+}
+
+// Pick 每次选择时所有节点的当前权重加上各自的权重，选中当前权重最大的节点，
+// 并将其当前权重减去总权重。这样选中的节点是平滑分布的，而不会连续选中权重大的节点。
+func (p *Picker) Pick(_ balancer.PickInfo) (balancer.PickResult, error) {
+	if len(p.nodes) == 0 {
+		return balancer.PickResult{}, balancer.ErrNoAvailableNode
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var total int64
+	var selected *weightedNode
+	for _, n := range p.nodes {
+		total += n.weight
+		n.currentWeight += n.weight
+		if selected == nil || n.currentWeight > selected.currentWeight {
+			selected = n
+		}
+	}
+	selected.currentWeight -= total
+
+	return balancer.PickResult{Node: selected.node}, nil
+}
@@ -0,0 +1,46 @@
+package weighted
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPicker(t *testing.T) {
+	nodes := []balancer.Node{
+		{Addr: "node-1", Weight: 5},
+		{Addr: "node-2", Weight: 1},
+		{Addr: "node-3", Weight: 1},
+	}
+	p := (&Builder{}).Build(nodes)
+
+	picked := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		res, err := p.Pick(balancer.PickInfo{Ctx: context.Background()})
+		require.NoError(t, err)
+		picked = append(picked, res.Node.Addr)
+	}
+	// 平滑加权轮询不会连续选中权重大的节点 5 次
+	assert.Equal(t, []string{"node-1", "node-1", "node-2", "node-1", "node-3", "node-1", "node-1"}, picked)
+
+	_, err := (&Builder{}).Build(nil).Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.ErrorIs(t, err, balancer.ErrNoAvailableNode)
+}
+
+func BenchmarkPicker(b *testing.B) {
+	p := (&Builder{}).Build([]balancer.Node{
+		{Addr: "node-1", Weight: 5},
+		{Addr: "node-2", Weight: 1},
+		{Addr: "node-3", Weight: 1},
+	})
+	info := balancer.PickInfo{Ctx: context.Background()}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = p.Pick(info)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package easyrpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JrMarcco/easy-rpc/breaker"
+	"github.com/JrMarcco/easy-rpc/message"
+)
+
+// breaker 获取目标地址对应的熔断器，未开启熔断时返回 nil。
+func (c *Client) breaker(addr string) *breaker.Breaker {
+	if c.breakerCfg == nil {
+		return nil
+	}
+	if val, ok := c.breakers.Load(addr); ok {
+		return val.(*breaker.Breaker)
+	}
+	val, _ := c.breakers.LoadOrStore(addr, breaker.NewBreaker(addr, *c.breakerCfg))
+	return val.(*breaker.Breaker)
+}
+
+// breakerResult 判断一次请求应当如何计入熔断器。
+//
+// 调用方主动取消（例如对冲请求中被取消的请求）无法说明服务端是否正常，不计入统计；
+// 服务端返回的业务错误不算失败，只有服务端不可用或者内部错误才算。
+func breakerResult(ctx context.Context, resp *message.Resp, err error) breaker.Result {
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return breaker.ResultIgnored
+		}
+		return breaker.ResultFailure
+	}
+
+	switch Code(resp.Status) {
+	case CodeUnavailable, CodeInternal:
+		return breaker.ResultFailure
+	default:
+		return breaker.ResultSuccess
+	}
+}
+
+// allBreakersOpen 判断是否所有节点的熔断器都处于 open 状态，此时重试必然再次被熔断器拒绝。
+func (c *Client) allBreakersOpen() bool {
+	if c.breakerCfg == nil {
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for addr := range c.addrs {
+		val, ok := c.breakers.Load(addr)
+		if !ok || val.(*breaker.Breaker).State() != breaker.StateOpen {
+			return false
+		}
+	}
+	return true
+}
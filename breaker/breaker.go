@@ -0,0 +1,279 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("[easy-rpc] circuit breaker is open")
+
+type State uint8
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("state(%d)", uint8(s))
+	}
+}
+
+// Config 熔断器配置。
+//
+// closed 状态下，满足以下任意条件时熔断（进入 open 状态）：
+// 1、连续失败次数达到 ConsecutiveFailures。
+// 2、滑动窗口内请求数不少于 MinRequests，且错误率达到 ErrorRate。
+//
+// open 状态持续 OpenTimeout 后进入 half-open 状态，最多放行 HalfOpenRequests 个探测请求：
+// 探测请求全部成功则恢复为 closed 状态，任意一个失败则重新进入 open 状态。
+type Config struct {
+	// 滑动窗口长度
+	Window time.Duration
+	// 滑动窗口分桶数，分桶越多统计越平滑
+	Buckets int
+
+	// 窗口内最少请求数，请求数不足时不按错误率熔断
+	MinRequests int
+	// 错误率阈值，取值 (0, 1]，小于等于 0 时不按错误率熔断
+	ErrorRate float64
+	// 连续失败次数阈值，小于等于 0 时不按连续失败次数熔断
+	ConsecutiveFailures int
+
+	// open 状态持续时间
+	OpenTimeout time.Duration
+	// half-open 状态允许的探测请求数
+	HalfOpenRequests int
+
+	// 状态变化回调，在状态变化后同步调用
+	OnStateChange func(name string, from State, to State)
+}
+
+// DefaultConfig 默认配置，10s 窗口内错误率达到 50% 或者连续失败 5 次时熔断。
+func DefaultConfig() Config {
+	return Config{
+		Window:              10 * time.Second,
+		Buckets:             10,
+		MinRequests:         20,
+		ErrorRate:           0.5,
+		ConsecutiveFailures: 5,
+		OpenTimeout:         5 * time.Second,
+		HalfOpenRequests:    3,
+	}
+}
+
+// Result 请求结果。
+type Result uint8
+
+const (
+	ResultSuccess Result = iota
+	ResultFailure
+	// ResultIgnored 不计入统计，例如调用方主动取消的请求，结果无法说明服务端是否正常；
+	// half-open 状态下只归还占用的探测名额
+	ResultIgnored
+)
+
+type bucket struct {
+	start    int64
+	total    int
+	failures int
+}
+
+type Breaker struct {
+	name string
+	cfg  Config
+
+	mu    sync.Mutex
+	state State
+	// 每次状态变化时递增，用于丢弃上一个状态下发出的请求结果
+	generation uint64
+
+	buckets     []bucket
+	bucketSize  int64
+	consecutive int
+
+	openedAt         time.Time
+	halfOpenInflight int
+	halfOpenSuccess  int
+
+	now func() time.Time
+}
+
+// Allow 判断请求是否允许通过。
+// 熔断时返回 ErrOpen；允许通过时，必须在请求结束后调用 done 上报请求结果。
+func (b *Breaker) Allow() (done func(result Result), err error) {
+	b.mu.Lock()
+
+	now := b.now()
+	var notify func()
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		notify = b.setState(StateHalfOpen, now)
+	}
+
+	switch b.state {
+	case StateOpen:
+		b.mu.Unlock()
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.halfOpenInflight+b.halfOpenSuccess >= b.cfg.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(notify)
+			return nil, ErrOpen
+		}
+		b.halfOpenInflight++
+	}
+
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(notify)
+
+	return func(result Result) {
+		b.done(generation, result)
+	}, nil
+}
+
+func (b *Breaker) done(generation uint64, result Result) {
+	b.mu.Lock()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	now := b.now()
+	var notify func()
+	switch b.state {
+	case StateClosed:
+		if result == ResultIgnored {
+			break
+		}
+		b.record(now, result == ResultSuccess)
+		if b.shouldTrip(now) {
+			notify = b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.halfOpenInflight--
+		if result == ResultIgnored {
+			break
+		}
+		if result == ResultFailure {
+			notify = b.setState(StateOpen, now)
+			break
+		}
+		b.halfOpenSuccess++
+		if b.halfOpenSuccess >= b.cfg.HalfOpenRequests {
+			notify = b.setState(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+	b.notify(notify)
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) record(now time.Time, success bool) {
+	if success {
+		b.consecutive = 0
+	} else {
+		b.consecutive++
+	}
+
+	start := now.UnixNano() / b.bucketSize
+	bkt := &b.buckets[start%int64(len(b.buckets))]
+	if bkt.start != start {
+		*bkt = bucket{start: start}
+	}
+	bkt.total++
+	if !success {
+		bkt.failures++
+	}
+}
+
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	if b.cfg.ConsecutiveFailures > 0 && b.consecutive >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	if b.cfg.ErrorRate <= 0 {
+		return false
+	}
+
+	// 只统计仍在窗口内的分桶
+	oldest := now.UnixNano()/b.bucketSize - int64(len(b.buckets)) + 1
+	total, failures := 0, 0
+	for _, bkt := range b.buckets {
+		if bkt.start >= oldest {
+			total += bkt.total
+			failures += bkt.failures
+		}
+	}
+	return total > 0 && total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.ErrorRate
+}
+
+// setState 切换状态并重置统计数据，返回的回调需要在释放锁之后执行。
+func (b *Breaker) setState(state State, now time.Time) func() {
+	from := b.state
+	b.state = state
+	b.generation++
+
+	b.consecutive = 0
+	b.halfOpenInflight = 0
+	b.halfOpenSuccess = 0
+	clear(b.buckets)
+
+	if state == StateOpen {
+		b.openedAt = now
+	}
+
+	if b.cfg.OnStateChange == nil {
+		return nil
+	}
+	return func() {
+		b.cfg.OnStateChange(b.name, from, state)
+	}
+}
+
+func (b *Breaker) notify(fn func()) {
+	if fn != nil {
+		fn()
+	}
+}
+
+func NewBreaker(name string, cfg Config) *Breaker {
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = 1
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+
+	bucketSize := int64(cfg.Window) / int64(cfg.Buckets)
+	if bucketSize <= 0 {
+		bucketSize = int64(time.Second)
+	}
+
+	return &Breaker{
+		name:       name,
+		cfg:        cfg,
+		buckets:    make([]bucket, cfg.Buckets),
+		bucketSize: bucketSize,
+		now:        time.Now,
+	}
+}
@@ -0,0 +1,223 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestBreaker(cfg Config) (*Breaker, *fakeClock, *[]State) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+
+	var transitions []State
+	cfg.OnStateChange = func(_ string, _ State, to State) {
+		transitions = append(transitions, to)
+	}
+
+	b := NewBreaker("test", cfg)
+	b.now = clock.Now
+	return b, clock, &transitions
+}
+
+func call(t *testing.T, b *Breaker, success bool) {
+	done, err := b.Allow()
+	require.NoError(t, err)
+	if success {
+		done(ResultSuccess)
+		return
+	}
+	done(ResultFailure)
+}
+
+func TestBreaker(t *testing.T) {
+	tcs := []struct {
+		name string
+		cfg  Config
+		// 依次上报的请求结果
+		results   []bool
+		wantState State
+	}{
+		{
+			name: "consecutive failures",
+			cfg: Config{
+				Window:              time.Second,
+				Buckets:             10,
+				ConsecutiveFailures: 3,
+				OpenTimeout:         time.Second,
+			},
+			results:   []bool{false, false, false},
+			wantState: StateOpen,
+		}, {
+			name: "success resets consecutive failures",
+			cfg: Config{
+				Window:              time.Second,
+				Buckets:             10,
+				ConsecutiveFailures: 3,
+				OpenTimeout:         time.Second,
+			},
+			results:   []bool{false, false, true, false, false},
+			wantState: StateClosed,
+		}, {
+			name: "error rate",
+			cfg: Config{
+				Window:      time.Second,
+				Buckets:     10,
+				MinRequests: 4,
+				ErrorRate:   0.5,
+				OpenTimeout: time.Second,
+			},
+			results:   []bool{true, false, true, false},
+			wantState: StateOpen,
+		}, {
+			name: "not enough requests",
+			cfg: Config{
+				Window:      time.Second,
+				Buckets:     10,
+				MinRequests: 4,
+				ErrorRate:   0.5,
+				OpenTimeout: time.Second,
+			},
+			results:   []bool{false, false, false},
+			wantState: StateClosed,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _, _ := newTestBreaker(tc.cfg)
+			for _, success := range tc.results {
+				call(t, b, success)
+			}
+			assert.Equal(t, tc.wantState, b.State())
+		})
+	}
+}
+
+func TestBreakerWindowExpire(t *testing.T) {
+	b, clock, _ := newTestBreaker(Config{
+		Window:      time.Second,
+		Buckets:     10,
+		MinRequests: 4,
+		ErrorRate:   0.5,
+		OpenTimeout: time.Second,
+	})
+
+	call(t, b, false)
+	call(t, b, false)
+	// 前两次失败已经滑出窗口
+	clock.Advance(2 * time.Second)
+	call(t, b, true)
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, true)
+
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	cfg := Config{
+		Window:              time.Second,
+		Buckets:             10,
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Second,
+		HalfOpenRequests:    2,
+	}
+
+	t.Run("recover", func(t *testing.T) {
+		b, clock, transitions := newTestBreaker(cfg)
+
+		call(t, b, false)
+		_, err := b.Allow()
+		require.ErrorIs(t, err, ErrOpen)
+
+		clock.Advance(time.Second)
+		assert.Equal(t, StateHalfOpen, b.State())
+
+		done1, err := b.Allow()
+		require.NoError(t, err)
+		done2, err := b.Allow()
+		require.NoError(t, err)
+		// 探测请求数已达上限
+		_, err = b.Allow()
+		require.ErrorIs(t, err, ErrOpen)
+
+		done1(ResultSuccess)
+		done2(ResultSuccess)
+		assert.Equal(t, StateClosed, b.State())
+		assert.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, *transitions)
+	})
+
+	t.Run("probe failed", func(t *testing.T) {
+		b, clock, transitions := newTestBreaker(cfg)
+
+		call(t, b, false)
+		clock.Advance(time.Second)
+
+		call(t, b, false)
+		assert.Equal(t, StateOpen, b.State())
+		assert.Equal(t, []State{StateOpen, StateHalfOpen, StateOpen}, *transitions)
+	})
+
+	t.Run("stale result", func(t *testing.T) {
+		b, clock, _ := newTestBreaker(cfg)
+
+		// closed 状态下发出的请求在 half-open 状态下才返回
+		done, err := b.Allow()
+		require.NoError(t, err)
+		call(t, b, false)
+		clock.Advance(time.Second)
+		call(t, b, true)
+
+		done(ResultFailure)
+		assert.Equal(t, StateHalfOpen, b.State())
+	})
+
+	t.Run("ignored probe", func(t *testing.T) {
+		b, clock, transitions := newTestBreaker(cfg)
+
+		call(t, b, false)
+		clock.Advance(time.Second)
+
+		// 被取消的探测请求不会关闭熔断器，只归还探测名额
+		done1, err := b.Allow()
+		require.NoError(t, err)
+		done2, err := b.Allow()
+		require.NoError(t, err)
+		done1(ResultIgnored)
+		done2(ResultIgnored)
+		assert.Equal(t, StateHalfOpen, b.State())
+
+		call(t, b, true)
+		call(t, b, true)
+		assert.Equal(t, StateClosed, b.State())
+		assert.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, *transitions)
+	})
+}
+
+func TestBreakerIgnored(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ConsecutiveFailures = 2
+	b, _, _ := newTestBreaker(cfg)
+
+	// 被忽略的结果不会重置连续失败次数
+	call(t, b, false)
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(ResultIgnored)
+	call(t, b, false)
+	assert.Equal(t, StateOpen, b.State())
+}
@@ -4,59 +4,252 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/JrMarcco/easy-rpc/balancer/roundrobin"
+	"github.com/JrMarcco/easy-rpc/breaker"
 	"github.com/JrMarcco/easy-rpc/compress"
 	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/registry"
 	"github.com/JrMarcco/easy-rpc/serialize"
 	"github.com/JrMarcco/easy-rpc/serialize/json"
 	"github.com/silenceper/pool"
 )
 
+// ErrClientClosed 客户端已经关闭
+var ErrClientClosed = errors.New("[easy-rpc] client closed")
+
 var _ Proxy = (*Client)(nil)
 
 type Client struct {
-	connPool   pool.Pool
+	mu     sync.RWMutex
+	closed bool
+	addrs  map[string]struct{}
+	pools  map[string]pool.Pool
+	// 每个分组独立负载均衡
+	pickers map[string]balancer.Picker
+
+	balancerBuilder balancer.Builder
+	poolFactory     func(addr string) (pool.Pool, error)
+
+	// 通过注册中心发现服务实例
+	registry    registry.Registry
+	serviceName string
+	instanceMu  sync.Mutex
+	instances   map[string]registry.ServiceInstance
+	filters     []registry.Filter
+	// 取消对注册中心的订阅
+	unsubscribe context.CancelFunc
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+
 	compressor compress.Compressor
 	serializer serialize.Serializer
+
+	caller string
+
+	defaultRetry  *RetryPolicy
+	retryPolicies map[string]RetryPolicy
+	idempotent    map[string]struct{}
+
+	hedgingPolicies map[string]HedgingPolicy
+	latencies       sync.Map
+
+	breakerCfg *breaker.Config
+	breakers   sync.Map
+
+	throttleCfg *throttleConfig
+	throttles   sync.Map
 }
 
-func (c *Client) Call(ctx context.Context, req *message.Req) (resp *message.Resp, err error) {
+type throttleConfig struct {
+	k      float64
+	window time.Duration
+}
+
+// Call 发送已经编码好的请求。
+// 请求的 Version 低于 message.VersionStatus 时按 VersionStatus 发送，保证响应中携带状态码，调用方的请求不会被修改。
+func (c *Client) Call(ctx context.Context, req *message.Req) (*message.Resp, error) {
+	if req.Version < message.VersionStatus {
+		versioned := *req
+		versioned.Version = message.VersionStatus
+		req = &versioned
+	}
+	return c.callWithPolicy(ctx, req, nil)
+}
+
+// callWithPolicy 发起调用，retry 不为空时使用指定的重试策略代替客户端配置的重试和对冲策略，
+// 并且视为幂等调用，见 WithCallRetryPolicy。
+func (c *Client) callWithPolicy(ctx context.Context, req *message.Req, retry *RetryPolicy) (*message.Resp, error) {
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	ch := make(chan struct{})
-	defer close(ch)
+	// oneway 调用不重试也不对冲
+	if isOneway(ctx) {
+		return c.call(ctx, req)
+	}
+	if retry != nil {
+		return c.callWithRetry(ctx, req, *retry)
+	}
+
+	// 非幂等方法不重试也不对冲
+	if !c.isIdempotent(ctx, req.Service, req.Method) {
+		return c.call(ctx, req)
+	}
 
+	if policy, ok := c.hedgingPolicy(req.Service, req.Method); ok && policy.MaxAttempts > 1 {
+		return c.callWithHedging(ctx, req, policy)
+	}
+	if policy, ok := c.retryPolicy(req.Service, req.Method); ok {
+		return c.callWithRetry(ctx, req, policy)
+	}
+	return c.call(ctx, req)
+}
+
+// callWithRetry 按重试策略发起调用，每次重试都会把当前的尝试次数写入 meta。
+func (c *Client) callWithRetry(ctx context.Context, req *message.Req, policy RetryPolicy) (*message.Resp, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.call(ctx, withAttempt(req, attempt))
+
+		var code Code
+		switch {
+		case ctx.Err() != nil:
+			// 超时或者被取消，没有必要继续重试
+			return resp, err
+		case errors.Is(err, breaker.ErrOpen) && c.allBreakersOpen():
+			// 重试同样会被熔断器拒绝
+			return resp, err
+		case errors.Is(err, ErrClientClosed):
+			return resp, err
+		case err != nil:
+			// 本地网络错误按 CodeUnavailable 处理
+			if code = CodeOf(err); code == CodeUnknown {
+				code = CodeUnavailable
+			}
+		default:
+			code = Code(resp.Status)
+		}
+
+		if code == CodeOK || attempt >= policy.MaxAttempts || !policy.retryable(code) {
+			return resp, err
+		}
+		if !policy.wait(ctx, attempt) {
+			return resp, err
+		}
+	}
+}
+
+// withAttempt 返回在 meta 中记录了第几次尝试的请求。
+// 第一次尝试直接使用原请求，之后每次尝试都复制请求和 meta，不修改调用方传入的请求。
+func withAttempt(req *message.Req, attempt int) *message.Req {
+	if attempt <= 1 {
+		return req
+	}
+
+	res := new(message.Req)
+	*res = *req
+	res.Meta = maps.Clone(req.Meta)
+	if res.Meta == nil {
+		res.Meta = make(map[string]string, 1)
+	}
+	res.Meta[metaKeyAttempt] = strconv.Itoa(attempt)
+	res.SetLength()
+	return res
+}
+
+func (c *Client) call(ctx context.Context, req *message.Req) (*message.Resp, error) {
+	type result struct {
+		resp *message.Resp
+		err  error
+	}
+
+	// 带缓冲，超时返回后发送协程也不会被阻塞
+	ch := make(chan result, 1)
 	go func() {
-		resp, err = c.sendRequest(ctx, req)
-		ch <- struct{}{}
+		resp, err := c.sendRequest(ctx, req)
+		ch <- result{resp: resp, err: err}
 	}()
 
 	select {
 	// 监听超时
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case <-ch:
-		return resp, err
+	case res := <-ch:
+		return res.resp, res.err
 	}
 }
 
-func (c *Client) sendRequest(ctx context.Context, req *message.Req) (*message.Resp, error) {
-	val, err := c.connPool.Get()
+func (c *Client) sendRequest(ctx context.Context, req *message.Req) (resp *message.Resp, err error) {
+	picked, err := c.pick(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("[easy-rpc] failed to get connection: %w", err)
+		return nil, err
+	}
+	addr := picked.Node.Addr
+
+	if picked.Done != nil {
+		start := time.Now()
+		defer func() {
+			picked.Done(balancer.DoneInfo{Err: err, Latency: time.Since(start)})
+		}()
+	}
+
+	// 服务端持续过载时，在客户端直接拒绝一部分请求
+	if th := c.throttle(addr); th != nil {
+		if !th.Allow() {
+			return nil, Errorf(CodeOverloaded, "[easy-rpc] request to %s throttled by client", addr)
+		}
+		defer func() {
+			// 只统计服务端的响应，网络错误和 oneway 调用无法说明服务端是否过载
+			if err == nil && !isOneway(ctx) {
+				th.Record(Code(resp.Status) != CodeOverloaded)
+			}
+		}()
+	}
+
+	if b := c.breaker(addr); b != nil {
+		done, allowErr := b.Allow()
+		if allowErr != nil {
+			return nil, fmt.Errorf("[easy-rpc] failed to send request to %s: %w", addr, allowErr)
+		}
+		// 注意这里的 resp 和 err 是命名返回值
+		defer func() {
+			done(breakerResult(ctx, resp, err))
+		}()
+	}
+
+	connPool, err := c.connPool(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := connPool.Get()
+	if err != nil {
+		return nil, fmt.Errorf("[easy-rpc] failed to get connection to %s: %w", addr, err)
 	}
-	defer func() {
-		_ = c.connPool.Put(val)
-	}()
 
 	conn := val.(net.Conn)
 
+	// ctx 结束时中断阻塞中的读写，避免被取消的请求一直占用连接
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer func() {
+		// 被中断或者读写失败的连接状态未知，不能放回连接池
+		if !stop() || err != nil {
+			_ = connPool.Close(val)
+			return
+		}
+		_ = connPool.Put(val)
+	}()
+
 	_, err = conn.Write(message.EncodeReq(req))
 	if err != nil {
 		return nil, err
@@ -76,6 +269,131 @@ func (c *Client) sendRequest(ctx context.Context, req *message.Req) (*message.Re
 	return message.DecodeResp(respBs), nil
 }
 
+// pick 通过负载均衡选择本次调用的节点。
+// 优先选择 context 中指定分组的节点，该分组没有节点时回退到默认分组。
+func (c *Client) pick(ctx context.Context, req *message.Req) (balancer.PickResult, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return balancer.PickResult{}, ErrClientClosed
+	}
+	picker, ok := c.pickers[GroupFromContext(ctx)]
+	if !ok {
+		picker, ok = c.pickers[registry.DefaultGroup]
+	}
+	c.mu.RUnlock()
+
+	if !ok {
+		return balancer.PickResult{}, Errorf(CodeUnavailable, "[easy-rpc] no available node for %s.%s", req.Service, req.Method)
+	}
+
+	res, err := picker.Pick(balancer.PickInfo{
+		Ctx:     ctx,
+		Service: req.Service,
+		Method:  req.Method,
+	})
+	if err != nil {
+		return res, Errorf(CodeUnavailable, "[easy-rpc] failed to pick node for %s.%s: %v", req.Service, req.Method, err)
+	}
+	return res, nil
+}
+
+// connPool 获取节点对应的连接池，连接池在第一次使用时创建。
+func (c *Client) connPool(addr string) (pool.Pool, error) {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+	if p, ok = c.pools[addr]; ok {
+		return p, nil
+	}
+	if _, ok = c.addrs[addr]; !ok {
+		return nil, Errorf(CodeUnavailable, "[easy-rpc] node %s has been removed", addr)
+	}
+
+	p, err := c.poolFactory(addr)
+	if err != nil {
+		return nil, fmt.Errorf("[easy-rpc] failed to create connection pool for %s: %w", addr, err)
+	}
+	c.pools[addr] = p
+	return p, nil
+}
+
+// updateNodes 按分组更新可用节点并重新构建 picker，同时释放已经下线节点的连接池，
+// 并移除这些节点的熔断器和自适应限流，节点重新上线时从初始状态开始统计。
+func (c *Client) updateNodes(groups map[string][]balancer.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 关闭之后处理的注册中心事件不再创建节点
+	if c.closed {
+		return
+	}
+
+	clear(c.addrs)
+	c.pickers = make(map[string]balancer.Picker, len(groups))
+	for group, nodes := range groups {
+		c.pickers[group] = c.balancerBuilder.Build(nodes)
+		for _, node := range nodes {
+			c.addrs[node.Addr] = struct{}{}
+		}
+	}
+
+	for addr, p := range c.pools {
+		if _, ok := c.addrs[addr]; !ok {
+			p.Release()
+			delete(c.pools, addr)
+		}
+	}
+	for _, m := range []*sync.Map{&c.breakers, &c.throttles} {
+		m.Range(func(key, _ any) bool {
+			if _, ok := c.addrs[key.(string)]; !ok {
+				m.Delete(key)
+			}
+			return true
+		})
+	}
+}
+
+// Close 停止监听注册中心并释放所有连接池，注册中心本身需要由调用方关闭。
+// 关闭之后的调用返回 ErrClientClosed。
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+		if c.unsubscribe != nil {
+			c.unsubscribe()
+		}
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	clear(c.addrs)
+	clear(c.pickers)
+	for addr, p := range c.pools {
+		p.Release()
+		delete(c.pools, addr)
+	}
+	return nil
+}
+
+// InitService 为服务结构体中的函数字段生成代理，调用函数即发起远程调用。
+//
+// 字段上可以通过 rpc 标签指定网络上的方法名以及调用选项，例如：
+//
+//	SayHello func(ctx context.Context, req *HelloReq) (*HelloResp, error) `rpc:"name=say_hello,timeout=200ms,retries=3"`
+//
+// 支持的选项见 clientMethodTag，标签不合法时 panic。
 func (c *Client) InitService(service Service) {
 	c.setProxyFunc(service)
 }
@@ -91,62 +409,70 @@ func (c *Client) setProxyFunc(service Service) {
 		if fdVal.CanSet() {
 			fd := typ.Field(i)
 
+			method, opts, err := clientMethodTag(fd)
+			if err != nil {
+				panic(fmt.Sprintf("[easy-rpc] invalid rpc tag on %s.%s: %v", typ.Name(), fd.Name, err))
+			}
+
 			fn := func(args []reflect.Value) []reflect.Value {
-				// req
+				// args[0] = context.Context, args[1] = req
+				ctx := args[0].Interface().(context.Context)
 				in := args[1].Interface()
 				// resp
 				out := reflect.New(fd.Type.Out(0).Elem()).Interface()
 
-				reqBody, err := c.serializer.Marshal(in)
-				if err != nil {
-					return []reflect.Value{reflect.ValueOf(out), reflect.ValueOf(err)}
+				errVal := reflect.Zero(errorType)
+				if err := c.Invoke(ctx, service.Name(), method, in, out, opts...); err != nil {
+					errVal = reflect.ValueOf(err)
 				}
+				return []reflect.Value{reflect.ValueOf(out), errVal}
+			}
+			fdVal.Set(reflect.MakeFunc(fd.Type, fn))
+		}
+	}
+}
 
-				// 压缩 request body
-				compressedBody, err := c.compressor.Compress(reqBody)
-				if err != nil {
-					return []reflect.Value{reflect.ValueOf(out), reflect.ValueOf(err)}
-				}
-
-				// args[0] = context.Context
-				ctx := args[0].Interface().(context.Context)
-
-				req := &message.Req{
-					Compressor: c.compressor.Code(),
-					Serializer: c.serializer.Code(),
-					Service:    service.Name(),
-					Method:     fd.Name,
-					Body:       compressedBody,
-					Meta:       c.metaFromContext(ctx),
-				}
-				req.SetLength()
-
-				resp, err := c.Call(ctx, req)
-				if err != nil {
-					return []reflect.Value{reflect.ValueOf(out), reflect.ValueOf(err)}
-				}
+// remoteError 将服务端回传的错误还原为 *Error。
+func remoteError(resp *message.Resp) error {
+	code := Code(resp.Status)
+	if code == CodeOK {
+		// 兼容没有回传状态码的服务端
+		code = CodeUnknown
+	}
+	return NewError(code, string(resp.Err))
+}
 
-				// 处理 Resp.Err（服务端回传的错误）
-				refErrVal := reflect.Zero(reflect.TypeOf(new(error)).Elem())
-				if len(resp.Err) != 0 {
-					remoteErr := errors.New(string(resp.Err))
-					if remoteErr != nil {
-						refErrVal = reflect.ValueOf(remoteErr)
-					}
-				}
+// callBuiltin 调用服务端的内置服务，内置服务的请求和响应固定使用 json 序列化并且不压缩。
+func (c *Client) callBuiltin(ctx context.Context, service, method string, in, out any) error {
+	return c.Invoke(ctx, service, method, in, out,
+		WithCallSerializer(&json.Serializer{}),
+		WithCallCompressor(&compress.DoNothing{}),
+	)
+}
 
-				if resp.BodyLen > 0 {
-					err = c.serializer.Unmarshal(resp.Body, out)
-					if err != nil {
-						return []reflect.Value{reflect.ValueOf(out), reflect.ValueOf(err)}
-					}
-				}
+// retryPolicy 按 method -> service -> 默认策略的顺序查找重试策略。
+func (c *Client) retryPolicy(service, method string) (RetryPolicy, bool) {
+	if policy, ok := c.retryPolicies[methodKey(service, method)]; ok {
+		return policy, true
+	}
+	if policy, ok := c.retryPolicies[service]; ok {
+		return policy, true
+	}
+	if c.defaultRetry != nil {
+		return *c.defaultRetry, true
+	}
+	return RetryPolicy{}, false
+}
 
-				return []reflect.Value{reflect.ValueOf(out), refErrVal}
-			}
-			fdVal.Set(reflect.MakeFunc(fd.Type, fn))
-		}
+func (c *Client) isIdempotent(ctx context.Context, service, method string) bool {
+	if isIdempotent(ctx) {
+		return true
+	}
+	if _, ok := c.idempotent[service]; ok {
+		return true
 	}
+	_, ok := c.idempotent[methodKey(service, method)]
+	return ok
 }
 
 // metaFromContext 通过 context 构建 meta 数据。
@@ -154,26 +480,82 @@ func (c *Client) metaFromContext(ctx context.Context) map[string]string {
 	meta := make(map[string]string, 2)
 	if dl, ok := ctx.Deadline(); ok {
 		// 设置了超时时间
-		meta[metaKeyDeadline] = strconv.FormatInt(dl.UnixMilli(), 10)
+		meta[MetaKeyDeadline] = strconv.FormatInt(dl.UnixMilli(), 10)
 	}
 	if isOneway(ctx) {
 		meta[metaKeyOneway] = "true"
 	}
+	if c.caller != "" {
+		meta[MetaKeyCaller] = c.caller
+	}
+	if group := GroupFromContext(ctx); group != registry.DefaultGroup {
+		meta[MetaKeyGroup] = group
+	}
 	return meta
 }
 
 type ClientBuilder struct {
-	addr       string
-	connPool   pool.Pool
+	addrs       []string
+	connPool    pool.Pool
+	poolFactory func(addr string) (pool.Pool, error)
+	balancer    balancer.Builder
+
+	registry    registry.Registry
+	serviceName string
+	filters     []registry.Filter
+
 	compressor compress.Compressor
 	serializer serialize.Serializer
+
+	caller string
+
+	defaultRetry  *RetryPolicy
+	retryPolicies map[string]RetryPolicy
+	idempotent    map[string]struct{}
+
+	hedgingPolicies map[string]HedgingPolicy
+
+	breakerCfg *breaker.Config
+
+	throttleCfg *throttleConfig
 }
 
+// ConnPool 指定连接池，只在客户端只有一个地址时使用。
 func (cb *ClientBuilder) ConnPool(pool pool.Pool) *ClientBuilder {
 	cb.connPool = pool
 	return cb
 }
 
+// ConnPoolFactory 指定每个节点的连接池的创建方式。
+func (cb *ClientBuilder) ConnPoolFactory(factory func(addr string) (pool.Pool, error)) *ClientBuilder {
+	cb.poolFactory = factory
+	return cb
+}
+
+// Registry 通过注册中心发现服务实例。
+//
+// 构建时通过 ListServices 拉取初始的服务实例，之后通过 Subscribe 监听实例变更，
+// 为每个实例维护独立的连接池，并将实例列表交给负载均衡选择。
+// 设置注册中心后，NewClientBuilder 指定的地址会被忽略。
+func (cb *ClientBuilder) Registry(r registry.Registry, serviceName string) *ClientBuilder {
+	cb.registry = r
+	cb.serviceName = serviceName
+	return cb
+}
+
+// Filter 设置服务实例过滤器，只有通过所有过滤器的实例才会参与负载均衡，
+// 例如 registry.MustParseFilter("version >= 2")。只对注册中心发现的实例生效。
+func (cb *ClientBuilder) Filter(filters ...registry.Filter) *ClientBuilder {
+	cb.filters = append(cb.filters, filters...)
+	return cb
+}
+
+// Balancer 指定负载均衡算法，默认为轮询。
+func (cb *ClientBuilder) Balancer(builder balancer.Builder) *ClientBuilder {
+	cb.balancer = builder
+	return cb
+}
+
 func (cb *ClientBuilder) Compressor(compressor compress.Compressor) *ClientBuilder {
 	cb.compressor = compressor
 	return cb
@@ -184,33 +566,163 @@ func (cb *ClientBuilder) Serializer(serializer serialize.Serializer) *ClientBuil
 	return cb
 }
 
+// Caller 设置调用方标识，随请求通过 meta 传递给服务端，服务端可以据此按调用方限流。
+func (cb *ClientBuilder) Caller(caller string) *ClientBuilder {
+	cb.caller = caller
+	return cb
+}
+
+// RetryPolicy 设置默认重试策略，对所有幂等方法生效。
+func (cb *ClientBuilder) RetryPolicy(policy RetryPolicy) *ClientBuilder {
+	cb.defaultRetry = &policy
+	return cb
+}
+
+// ServiceRetryPolicy 设置服务级别的重试策略，优先级高于默认策略。
+func (cb *ClientBuilder) ServiceRetryPolicy(service string, policy RetryPolicy) *ClientBuilder {
+	cb.retryPolicies[service] = policy
+	return cb
+}
+
+// MethodRetryPolicy 设置方法级别的重试策略，优先级高于服务级别策略。
+func (cb *ClientBuilder) MethodRetryPolicy(service string, method string, policy RetryPolicy) *ClientBuilder {
+	cb.retryPolicies[methodKey(service, method)] = policy
+	return cb
+}
+
+// ServiceHedgingPolicy 设置服务级别的对冲策略，只对幂等方法生效。
+func (cb *ClientBuilder) ServiceHedgingPolicy(service string, policy HedgingPolicy) *ClientBuilder {
+	cb.hedgingPolicies[service] = policy
+	return cb
+}
+
+// MethodHedgingPolicy 设置方法级别的对冲策略，优先级高于服务级别策略。
+func (cb *ClientBuilder) MethodHedgingPolicy(service string, method string, policy HedgingPolicy) *ClientBuilder {
+	cb.hedgingPolicies[methodKey(service, method)] = policy
+	return cb
+}
+
+// CircuitBreaker 开启熔断，每个目标地址使用独立的熔断器。
+// 熔断期间的调用直接返回 breaker.ErrOpen。
+func (cb *ClientBuilder) CircuitBreaker(cfg breaker.Config) *ClientBuilder {
+	cb.breakerCfg = &cfg
+	return cb
+}
+
+// AdaptiveThrottle 开启客户端自适应限流，每个目标地址独立统计。
+// 服务端持续返回 CodeOverloaded 时，客户端按比例直接拒绝请求，k 一般取 2，越小越激进。
+func (cb *ClientBuilder) AdaptiveThrottle(k float64, window time.Duration) *ClientBuilder {
+	cb.throttleCfg = &throttleConfig{k: k, window: window}
+	return cb
+}
+
+// Idempotent 将方法标记为幂等，只有幂等方法才允许重试。
+// 不指定 methods 时将整个服务的所有方法都标记为幂等。
+func (cb *ClientBuilder) Idempotent(service string, methods ...string) *ClientBuilder {
+	if len(methods) == 0 {
+		cb.idempotent[service] = struct{}{}
+		return cb
+	}
+	for _, method := range methods {
+		cb.idempotent[methodKey(service, method)] = struct{}{}
+	}
+	return cb
+}
+
 func (cb *ClientBuilder) Build() (*Client, error) {
-	if cb.connPool == nil {
-		connPool, err := pool.NewChannelPool(&pool.Config{
-			InitialCap:  8,
-			MaxCap:      64,
-			MaxIdle:     16,
-			IdleTimeout: time.Minute,
-			Factory:     func() (any, error) { return net.Dial("tcp", cb.addr) },
-			Close:       func(val any) error { return val.(net.Conn).Close() },
-		})
-		if err != nil {
-			return nil, fmt.Errorf("[easy-rpc] failed to create connection pool: %w", err)
-		}
-		cb.connPool = connPool
+	if len(cb.addrs) == 0 && cb.registry == nil {
+		return nil, errors.New("[easy-rpc] at least one address or a registry is required")
 	}
 
-	return &Client{
-		connPool:   cb.connPool,
+	client := &Client{
+		addrs:           make(map[string]struct{}, len(cb.addrs)),
+		pools:           make(map[string]pool.Pool, len(cb.addrs)),
+		balancerBuilder: cb.balancer,
+		poolFactory:     cb.poolFactory,
+
+		registry:    cb.registry,
+		serviceName: cb.serviceName,
+		instances:   make(map[string]registry.ServiceInstance),
+		filters:     cb.filters,
+
+		closeCh: make(chan struct{}),
+
 		compressor: cb.compressor,
 		serializer: cb.serializer,
-	}, nil
+
+		caller: cb.caller,
+
+		defaultRetry:  cb.defaultRetry,
+		retryPolicies: cb.retryPolicies,
+		idempotent:    cb.idempotent,
+
+		hedgingPolicies: cb.hedgingPolicies,
+
+		breakerCfg: cb.breakerCfg,
+
+		throttleCfg: cb.throttleCfg,
+	}
+
+	if cb.registry != nil {
+		// 先订阅再拉取全量实例，避免错过两者之间的变更
+		ctx, cancel := context.WithCancel(context.Background())
+		client.unsubscribe = cancel
+		events := cb.registry.Subscribe(ctx, cb.serviceName)
+		if err := client.resolve(); err != nil {
+			cancel()
+			return nil, err
+		}
+		go client.watch(events)
+		return client, nil
+	}
+
+	nodes := make([]balancer.Node, 0, len(cb.addrs))
+	for _, addr := range cb.addrs {
+		nodes = append(nodes, balancer.Node{Addr: addr})
+	}
+	// 直接指定的地址都属于默认分组
+	client.updateNodes(map[string][]balancer.Node{registry.DefaultGroup: nodes})
+
+	if cb.connPool != nil && len(cb.addrs) == 1 {
+		client.pools[cb.addrs[0]] = cb.connPool
+	}
+
+	// 预先创建连接池，地址不可用时尽早返回错误
+	for _, addr := range cb.addrs {
+		if _, err := client.connPool(addr); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// defaultPoolFactory 默认的连接池
+func defaultPoolFactory(addr string) (pool.Pool, error) {
+	return pool.NewChannelPool(&pool.Config{
+		InitialCap:  8,
+		MaxCap:      64,
+		MaxIdle:     16,
+		IdleTimeout: time.Minute,
+		Factory:     func() (any, error) { return net.Dial("tcp", addr) },
+		Close:       func(val any) error { return val.(net.Conn).Close() },
+	})
 }
 
-func NewClientBuilder(addr string) *ClientBuilder {
+// NewClientBuilder 创建客户端，有多个地址时通过负载均衡选择每次调用的地址。
+// 通过注册中心发现服务实例时可以不指定地址，见 ClientBuilder.Registry。
+func NewClientBuilder(addrs ...string) *ClientBuilder {
 	return &ClientBuilder{
-		addr:       addr,
+		addrs:       addrs,
+		poolFactory: defaultPoolFactory,
+		balancer:    &roundrobin.Builder{},
+
 		compressor: &compress.DoNothing{},
 		serializer: &json.Serializer{},
+
+		retryPolicies: make(map[string]RetryPolicy),
+		idempotent:    make(map[string]struct{}),
+
+		hedgingPolicies: make(map[string]HedgingPolicy),
 	}
 }
@@ -0,0 +1,386 @@
+// easyrpc 命令行客户端，用于调试线上服务，不需要为每次调试编写客户端代码。
+//
+// 用法：
+//
+//	easyrpc [flags] service.method [json-body | -]
+//	easyrpc [flags] -list [service]
+//
+// 例如：
+//
+//	easyrpc -addr 127.0.0.1:8081 -meta caller=debug -timeout 3s user-service.GetById '{"Id": 1}'
+//	easyrpc -etcd 127.0.0.1:2379 -etcd-namespace prod -serializer proto user-service.GetById '{"id": 1}'
+//	echo '{"Id": 1}' | easyrpc -addr 127.0.0.1:8081 -compressor gzip user-service.GetById -
+//
+// 调用成功时响应以 json 格式输出到标准输出；调用失败时错误以 json 格式输出到标准错误，
+// 包含状态码的名称、数值以及错误信息，退出码为 1。参数错误时退出码为 2。
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/compress"
+	"github.com/JrMarcco/easy-rpc/compress/gzip"
+	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/registry/etcd"
+	"github.com/JrMarcco/easy-rpc/serialize"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+var serializerCodes = map[string]uint8{
+	"json":  serialize.SerializerJson,
+	"proto": serialize.SerializerProto,
+}
+
+var compressorCodes = map[string]uint8{
+	"none": compress.CompressorNone,
+	"gzip": compress.CompressorGzip,
+}
+
+type config struct {
+	addr          string
+	etcdEndpoints string
+	etcdPrefix    string
+	etcdNamespace string
+
+	serializer uint8
+	compressor uint8
+	meta       map[string]string
+	timeout    time.Duration
+
+	list bool
+	args []string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		_, _ = fmt.Fprintf(stderr, "easyrpc: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+
+	var out []byte
+	if cfg.list {
+		out, err = list(ctx, cfg)
+	} else {
+		out, err = invoke(ctx, cfg, stdin)
+	}
+	if err != nil {
+		writeError(stderr, err)
+		return exitError
+	}
+
+	var buf bytes.Buffer
+	if json.Indent(&buf, out, "", "  ") != nil {
+		buf.Reset()
+		buf.Write(out)
+	}
+	buf.WriteByte('\n')
+	_, _ = stdout.Write(buf.Bytes())
+	return exitOK
+}
+
+func parseFlags(args []string, output io.Writer) (*config, error) {
+	cfg := &config{meta: make(map[string]string)}
+
+	fs := flag.NewFlagSet("easyrpc", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(output, "usage: easyrpc [flags] service.method [json-body | -]")
+		_, _ = fmt.Fprintln(output, "       easyrpc [flags] -list [service]")
+		fs.PrintDefaults()
+	}
+
+	var serializer, compressor string
+	fs.StringVar(&cfg.addr, "addr", "", "server address, e.g. 127.0.0.1:8081")
+	fs.StringVar(&cfg.etcdEndpoints, "etcd", "", "comma separated etcd endpoints, resolve the service through the etcd registry instead of -addr")
+	fs.StringVar(&cfg.etcdPrefix, "etcd-prefix", "", "key prefix of the etcd registry (default /easyrpc)")
+	fs.StringVar(&cfg.etcdNamespace, "etcd-namespace", "", "namespace of the etcd registry")
+	fs.StringVar(&serializer, "serializer", "json", "serializer, json, proto or a serializer code")
+	fs.StringVar(&compressor, "compressor", "none", "compressor of the request body, none, gzip or a compressor code")
+	fs.Var((*metaFlag)(&cfg.meta), "meta", "request meta in key=value form, can be repeated")
+	fs.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "timeout of the whole call")
+	fs.BoolVar(&cfg.list, "list", false, "list services and methods through the reflection service")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	cfg.args = fs.Args()
+
+	if (cfg.addr == "") == (cfg.etcdEndpoints == "") {
+		return nil, errors.New("exactly one of -addr and -etcd is required")
+	}
+	if cfg.timeout <= 0 {
+		return nil, errors.New("-timeout must be positive")
+	}
+
+	var err error
+	if cfg.serializer, err = parseCode(serializer, serializerCodes); err != nil {
+		return nil, fmt.Errorf("invalid -serializer: %w", err)
+	}
+	if cfg.compressor, err = parseCode(compressor, compressorCodes); err != nil {
+		return nil, fmt.Errorf("invalid -compressor: %w", err)
+	}
+
+	switch {
+	case cfg.list && len(cfg.args) > 1:
+		return nil, errors.New("-list accepts at most one service")
+	case cfg.list && cfg.etcdEndpoints != "" && len(cfg.args) == 0:
+		return nil, errors.New("-list with -etcd requires a service")
+	case !cfg.list && (len(cfg.args) == 0 || len(cfg.args) > 2):
+		return nil, errors.New("expected service.method and an optional json body")
+	}
+	return cfg, nil
+}
+
+// parseCode 解析序列化或者压缩算法，支持名称或者直接指定编码。
+func parseCode(val string, codes map[string]uint8) (uint8, error) {
+	if code, ok := codes[strings.ToLower(val)]; ok {
+		return code, nil
+	}
+	code, err := strconv.ParseUint(val, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unknown %q", val)
+	}
+	return uint8(code), nil
+}
+
+// parseTarget 解析 service.method，服务名本身可以包含 '.'，以最后一个 '.' 分隔。
+func parseTarget(target string) (service, method string, err error) {
+	idx := strings.LastIndexByte(target, '.')
+	if idx <= 0 || idx == len(target)-1 {
+		return "", "", fmt.Errorf("invalid target %q, expected service.method", target)
+	}
+	return target[:idx], target[idx+1:], nil
+}
+
+type metaFlag map[string]string
+
+func (m *metaFlag) String() string {
+	if m == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*m))
+	for k, v := range *m {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *metaFlag) Set(val string) error {
+	k, v, ok := strings.Cut(val, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("invalid meta %q, expected key=value", val)
+	}
+	if strings.ContainsAny(val, "\n\t") {
+		// 换行符和制表符是 meta 编码时的分隔符
+		return fmt.Errorf("invalid meta %q, newline and tab are not allowed", val)
+	}
+	(*m)[k] = v
+	return nil
+}
+
+// readBody 读取请求体，"-" 表示从标准输入读取，没有指定时为空对象。
+func readBody(args []string, stdin io.Reader) ([]byte, error) {
+	if len(args) == 0 {
+		return []byte("{}"), nil
+	}
+	body := []byte(args[0])
+	if args[0] == "-" {
+		var err error
+		if body, err = io.ReadAll(stdin); err != nil {
+			return nil, fmt.Errorf("failed to read body from stdin: %w", err)
+		}
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return []byte("{}"), nil
+	}
+	if !json.Valid(body) {
+		return nil, easyrpc.NewError(easyrpc.CodeInvalidArgument, "request body is not valid json")
+	}
+	return body, nil
+}
+
+// newClient 创建客户端，指定了 -etcd 时通过 etcd 注册中心发现 service 的实例。
+// 返回的 close 函数同时关闭客户端以及注册中心。
+func newClient(cfg *config, service string) (*easyrpc.Client, func(), error) {
+	if cfg.etcdEndpoints == "" {
+		client, err := easyrpc.NewClientBuilder(cfg.addr).Build()
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() { _ = client.Close() }, nil
+	}
+
+	etcdClient, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(cfg.etcdEndpoints, ","),
+		DialTimeout: cfg.timeout,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to etcd: %w", err)
+	}
+
+	rb := etcd.NewRegistryBuilder(etcdClient).Namespace(cfg.etcdNamespace)
+	if cfg.etcdPrefix != "" {
+		rb = rb.Prefix(cfg.etcdPrefix)
+	}
+	r, err := rb.Build()
+	if err != nil {
+		_ = etcdClient.Close()
+		return nil, nil, err
+	}
+
+	client, err := easyrpc.NewClientBuilder().Registry(r, service).Build()
+	if err != nil {
+		_ = r.Close()
+		_ = etcdClient.Close()
+		return nil, nil, err
+	}
+	return client, func() {
+		_ = client.Close()
+		_ = r.Close()
+		_ = etcdClient.Close()
+	}, nil
+}
+
+func invoke(ctx context.Context, cfg *config, stdin io.Reader) ([]byte, error) {
+	service, method, err := parseTarget(cfg.args[0])
+	if err != nil {
+		return nil, easyrpc.NewError(easyrpc.CodeInvalidArgument, err.Error())
+	}
+	body, err := readBody(cfg.args[1:], stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	client, closeFn, err := newClient(cfg, service)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	// proto 序列化时通过反射服务获取消息的描述，在 json 和 proto 之间转换
+	var codec *protoCodec
+	if cfg.serializer == serialize.SerializerProto {
+		if codec, err = newProtoCodec(ctx, client, service, method); err != nil {
+			return nil, err
+		}
+		if body, err = codec.marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.compressor == compress.CompressorGzip {
+		if body, err = (&gzip.Compressor{}).Compress(body); err != nil {
+			return nil, fmt.Errorf("failed to compress request body: %w", err)
+		}
+	}
+
+	req := &message.Req{
+		Compressor: cfg.compressor,
+		Serializer: cfg.serializer,
+		Service:    service,
+		Method:     method,
+		Body:       body,
+		Meta:       requestMeta(ctx, cfg.meta),
+	}
+	req.SetLength()
+
+	resp, err := client.Call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Err) != 0 || resp.Status != uint8(easyrpc.CodeOK) {
+		code := easyrpc.Code(resp.Status)
+		if code == easyrpc.CodeOK {
+			code = easyrpc.CodeUnknown
+		}
+		return nil, easyrpc.NewError(code, string(resp.Err))
+	}
+
+	if codec != nil {
+		return codec.unmarshal(resp.Body)
+	}
+	return resp.Body, nil
+}
+
+// requestMeta 合并用户指定的 meta 以及超时时间，服务端据此设置处理请求的超时。
+func requestMeta(ctx context.Context, meta map[string]string) map[string]string {
+	res := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		res[k] = v
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		res[easyrpc.MetaKeyDeadline] = strconv.FormatInt(dl.UnixMilli(), 10)
+	}
+	return res
+}
+
+func list(ctx context.Context, cfg *config) ([]byte, error) {
+	var service string
+	if len(cfg.args) > 0 {
+		service = cfg.args[0]
+	}
+
+	client, closeFn, err := newClient(cfg, service)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+
+	services, err := client.Reflect(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	// proto 文件描述是二进制内容，输出时省略
+	for i := range services {
+		for j := range services[i].Methods {
+			services[i].Methods[j].Request.ProtoFiles = nil
+			services[i].Methods[j].Response.ProtoFiles = nil
+		}
+	}
+	return json.Marshal(services)
+}
+
+type errorOutput struct {
+	Code    string `json:"code"`
+	CodeNum uint8  `json:"code_num"`
+	Message string `json:"message"`
+}
+
+func writeError(w io.Writer, err error) {
+	code := easyrpc.CodeOf(err)
+	bs, _ := json.MarshalIndent(errorOutput{
+		Code:    code.String(),
+		CodeNum: uint8(code),
+		Message: err.Error(),
+	}, "", "  ")
+	_, _ = fmt.Fprintf(w, "%s\n", bs)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/compress"
+	"github.com/JrMarcco/easy-rpc/serialize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlags(t *testing.T) {
+	tcs := []struct {
+		name    string
+		args    []string
+		wantCfg *config
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			args: []string{"-addr", ":8081", "user-service.GetById"},
+			wantCfg: &config{
+				addr:       ":8081",
+				serializer: serialize.SerializerJson,
+				compressor: compress.CompressorNone,
+				meta:       map[string]string{},
+				timeout:    5 * time.Second,
+				args:       []string{"user-service.GetById"},
+			},
+		},
+		{
+			name: "all flags",
+			args: []string{
+				"-etcd", "127.0.0.1:2379", "-etcd-prefix", "/rpc", "-etcd-namespace", "prod",
+				"-serializer", "proto", "-compressor", "1", "-meta", "caller=debug", "-meta", "trace=abc",
+				"-timeout", "200ms", "user-service.GetById", `{"id": 1}`,
+			},
+			wantCfg: &config{
+				etcdEndpoints: "127.0.0.1:2379",
+				etcdPrefix:    "/rpc",
+				etcdNamespace: "prod",
+				serializer:    serialize.SerializerProto,
+				compressor:    compress.CompressorGzip,
+				meta:          map[string]string{"caller": "debug", "trace": "abc"},
+				timeout:       200 * time.Millisecond,
+				args:          []string{"user-service.GetById", `{"id": 1}`},
+			},
+		},
+		{
+			name: "list",
+			args: []string{"-addr", ":8081", "-list"},
+			wantCfg: &config{
+				addr:       ":8081",
+				serializer: serialize.SerializerJson,
+				compressor: compress.CompressorNone,
+				meta:       map[string]string{},
+				timeout:    5 * time.Second,
+				list:       true,
+				args:       []string{},
+			},
+		},
+		{name: "no address", args: []string{"user-service.GetById"}, wantErr: true},
+		{name: "both address and etcd", args: []string{"-addr", ":8081", "-etcd", ":2379", "user-service.GetById"}, wantErr: true},
+		{name: "no target", args: []string{"-addr", ":8081"}, wantErr: true},
+		{name: "too many args", args: []string{"-addr", ":8081", "a.b", "{}", "{}"}, wantErr: true},
+		{name: "unknown serializer", args: []string{"-addr", ":8081", "-serializer", "xml", "a.b"}, wantErr: true},
+		{name: "invalid meta", args: []string{"-addr", ":8081", "-meta", "caller", "a.b"}, wantErr: true},
+		{name: "list with etcd", args: []string{"-etcd", ":2379", "-list"}, wantErr: true},
+		{name: "invalid timeout", args: []string{"-addr", ":8081", "-timeout", "0s", "a.b"}, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := parseFlags(tc.args, io.Discard)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCfg, cfg)
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tcs := []struct {
+		target      string
+		wantService string
+		wantMethod  string
+		wantErr     bool
+	}{
+		{target: "user-service.GetById", wantService: "user-service", wantMethod: "GetById"},
+		{target: "easyrpc.health.Check", wantService: "easyrpc.health", wantMethod: "Check"},
+		{target: "user-service", wantErr: true},
+		{target: ".GetById", wantErr: true},
+		{target: "user-service.", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.target, func(t *testing.T) {
+			service, method, err := parseTarget(tc.target)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantService, service)
+			assert.Equal(t, tc.wantMethod, method)
+		})
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	tcs := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "no body", want: "{}"},
+		{name: "argument", args: []string{`{"Id": 1}`}, want: `{"Id": 1}`},
+		{name: "stdin", args: []string{"-"}, stdin: " {\"Id\": 1}\n", want: `{"Id": 1}`},
+		{name: "empty stdin", args: []string{"-"}, want: "{}"},
+		{name: "invalid json", args: []string{`{"Id": `}, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := readBody(tc.args, strings.NewReader(tc.stdin))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(body))
+		})
+	}
+}
+
+func TestRunError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-addr", ":8081", "user-service.GetById", `{"Id": `}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, exitError, code)
+	assert.Empty(t, stdout.String())
+
+	out := errorOutput{}
+	require.NoError(t, json.Unmarshal(stderr.Bytes(), &out))
+	assert.Equal(t, errorOutput{Code: "invalid argument", CodeNum: 4, Message: "request body is not valid json"}, out)
+
+	stderr.Reset()
+	code = run([]string{"user-service.GetById"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, exitUsage, code)
+}
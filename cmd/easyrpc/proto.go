@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoCodec 在 json 和 proto 之间转换请求和响应，消息的描述通过服务端的反射服务获取。
+type protoCodec struct {
+	req  protoreflect.MessageDescriptor
+	resp protoreflect.MessageDescriptor
+}
+
+func newProtoCodec(ctx context.Context, client *easyrpc.Client, service, method string) (*protoCodec, error) {
+	services, err := client.Reflect(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch proto descriptors, is reflection enabled on the server: %w", err)
+	}
+
+	for _, info := range services {
+		for _, m := range info.Methods {
+			if m.Name != method {
+				continue
+			}
+
+			req, err := messageDescriptor(m.Request)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := messageDescriptor(m.Response)
+			if err != nil {
+				return nil, err
+			}
+			return &protoCodec{req: req, resp: resp}, nil
+		}
+	}
+	return nil, easyrpc.Errorf(easyrpc.CodeNotFound, "method %s.%s not found", service, method)
+}
+
+func messageDescriptor(info easyrpc.TypeInfo) (protoreflect.MessageDescriptor, error) {
+	if info.ProtoName == "" {
+		return nil, easyrpc.Errorf(easyrpc.CodeInvalidArgument, "%s is not a proto message", info.GoType)
+	}
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, bs := range info.ProtoFiles {
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(bs, fd); err != nil {
+			return nil, fmt.Errorf("invalid proto file descriptor of %s: %w", info.ProtoName, err)
+		}
+		fds.File = append(fds.File, fd)
+	}
+
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proto file descriptor of %s: %w", info.ProtoName, err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(info.ProtoName))
+	if err != nil {
+		return nil, fmt.Errorf("proto message %s not found: %w", info.ProtoName, err)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a proto message", info.ProtoName)
+	}
+	return md, nil
+}
+
+// marshal 将 json 格式的请求转换为 proto 编码。
+func (c *protoCodec) marshal(body []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(c.req)
+	if err := protojson.Unmarshal(body, msg); err != nil {
+		return nil, easyrpc.Errorf(easyrpc.CodeInvalidArgument, "invalid request body for %s: %v", c.req.FullName(), err)
+	}
+	return proto.Marshal(msg)
+}
+
+// unmarshal 将 proto 编码的响应转换为 json 格式。
+func (c *protoCodec) unmarshal(body []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(c.resp)
+	if err := proto.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode response as %s: %w", c.resp.FullName(), err)
+	}
+	return protojson.Marshal(msg)
+}
@@ -1,6 +1,10 @@
 package easyrpc
 
-import "context"
+import (
+	"context"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+)
 
 type contextKeyOneway struct{}
 
@@ -12,3 +16,46 @@ func isOneway(ctx context.Context) bool {
 	val, ok := ctx.Value(contextKeyOneway{}).(bool)
 	return ok && val
 }
+
+type contextKeyIdempotent struct{}
+
+// ContextWithIdempotent 将本次调用标记为幂等，允许按重试策略重试。
+func ContextWithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyIdempotent{}, true)
+}
+
+func isIdempotent(ctx context.Context) bool {
+	val, ok := ctx.Value(contextKeyIdempotent{}).(bool)
+	return ok && val
+}
+
+type contextKeyAttempt struct{}
+
+// AttemptFromContext 获取服务端当前请求的尝试次数，首次调用为 1，重试时递增。
+func AttemptFromContext(ctx context.Context) int {
+	val, ok := ctx.Value(contextKeyAttempt{}).(int)
+	if !ok {
+		return 1
+	}
+	return val
+}
+
+type contextKeyGroup struct{}
+
+// ContextWithGroup 指定调用路由到的分组，例如灰度发布时的 canary 分组。
+// 客户端优先选择该分组的服务实例，没有匹配的实例时回退到默认分组。
+//
+// 分组会随请求传递给服务端，服务端处理请求时的 context 同样带有该分组，
+// 因此在服务端内发起的链式调用也会路由到同一分组。
+func ContextWithGroup(ctx context.Context, group string) context.Context {
+	return context.WithValue(ctx, contextKeyGroup{}, group)
+}
+
+// GroupFromContext 获取调用路由到的分组，没有指定时返回 registry.DefaultGroup。
+func GroupFromContext(ctx context.Context) string {
+	group, ok := ctx.Value(contextKeyGroup{}).(string)
+	if !ok || group == "" {
+		return registry.DefaultGroup
+	}
+	return group
+}
@@ -0,0 +1,115 @@
+package easyrpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/balancer"
+	"github.com/JrMarcco/easy-rpc/registry"
+)
+
+// registryTimeout 从注册中心拉取服务实例的超时时间
+const registryTimeout = 3 * time.Second
+
+// resolve 从注册中心拉取全量的服务实例，并刷新可用节点。
+func (c *Client) resolve() error {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	instances, err := c.registry.ListServices(ctx, c.serviceName)
+	if err != nil {
+		return fmt.Errorf("[easy-rpc] failed to list instances of service %s: %w", c.serviceName, err)
+	}
+
+	c.instanceMu.Lock()
+	defer c.instanceMu.Unlock()
+
+	clear(c.instances)
+	for _, instance := range instances {
+		c.instances[instance.Addr] = instance
+	}
+	c.refreshNodes()
+	return nil
+}
+
+// watch 监听注册中心的服务实例变更，直到客户端关闭。
+func (c *Client) watch(events <-chan registry.Event) {
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			c.handleEvent(event)
+		}
+	}
+}
+
+func (c *Client) handleEvent(event registry.Event) {
+	if event.Type == registry.EventTypeSnapshot {
+		c.instanceMu.Lock()
+		defer c.instanceMu.Unlock()
+
+		clear(c.instances)
+		for _, instance := range event.Instances {
+			c.instances[instance.Addr] = instance
+		}
+		c.refreshNodes()
+		return
+	}
+
+	instance := event.ServiceInstance
+	if instance.Addr == "" {
+		// 事件中没有携带服务实例，只能重新拉取全量实例。
+		// 拉取失败时保留当前的实例列表，等待下一个事件。
+		_ = c.resolve()
+		return
+	}
+
+	c.instanceMu.Lock()
+	defer c.instanceMu.Unlock()
+
+	switch event.Type {
+	case registry.EventTypePut:
+		c.instances[instance.Addr] = instance
+	case registry.EventTypeDel:
+		delete(c.instances, instance.Addr)
+	default:
+		return
+	}
+	c.refreshNodes()
+}
+
+// refreshNodes 根据当前的服务实例按分组刷新可用节点，调用方需要持有 instanceMu。
+// draining 和 unhealthy 的实例不会再收到新的请求，已经发出的请求不受影响。
+func (c *Client) refreshNodes() {
+	groups := make(map[string][]balancer.Node, 2)
+	for _, instance := range c.instances {
+		if instance.Status != registry.StatusServing || !c.accept(instance) {
+			continue
+		}
+
+		group := instance.Group
+		if group == "" {
+			group = registry.DefaultGroup
+		}
+		groups[group] = append(groups[group], balancer.Node{
+			Addr:   instance.Addr,
+			Weight: instance.Weight,
+		})
+	}
+	c.updateNodes(groups)
+}
+
+// accept 判断服务实例是否通过了所有过滤器。
+func (c *Client) accept(instance registry.ServiceInstance) bool {
+	for _, filter := range c.filters {
+		if !filter(instance) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package easyrpc
+
+import (
+	"context"
+	"reflect"
+)
+
+// Handler 处理一个方法的请求，不经过反射分发。
+//
+// decode 将请求体反序列化到 in 中，失败时返回 CodeInvalidArgument 的错误；
+// 返回值会使用请求的序列化协议序列化为响应体。
+type Handler func(ctx context.Context, decode func(in any) error) (any, error)
+
+// methodHandler 通过 RegisterHandler 注册的方法。
+type methodHandler struct {
+	handler Handler
+	// 请求和响应的指针类型，只用于反射服务，通过 RegisterHandler 注册时为空
+	reqType  reflect.Type
+	respType reflect.Type
+}
+
+var _ Service = (*handlerService)(nil)
+
+// handlerService 只通过 RegisterHandler 注册方法的服务。
+type handlerService struct {
+	name string
+}
+
+func (h *handlerService) Name() string {
+	return h.name
+}
+
+// RegisterHandler 注册服务的一个方法，服务不存在时创建服务，已经存在的同名方法会被覆盖。
+//
+// 通过 RegisterService 注册的服务也可以通过 RegisterHandler 添加或者覆盖方法，
+// handler 优先于服务结构体上的同名方法。
+func (s *Server) RegisterHandler(service, method string, handler Handler) {
+	s.registerHandler(service, method, &methodHandler{handler: handler})
+}
+
+// Handle 注册强类型的方法，请求和响应的类型可以通过反射服务查询。
+//
+//	easyrpc.Handle(svr, "user-service", "SayHello", func(ctx context.Context, req *HelloReq) (*HelloResp, error) {
+//		return &HelloResp{Msg: "hello " + req.Name}, nil
+//	})
+func Handle[Req, Resp any](s *Server, service, method string, fn func(ctx context.Context, req *Req) (*Resp, error)) {
+	s.registerHandler(service, method, &methodHandler{
+		handler: func(ctx context.Context, decode func(in any) error) (any, error) {
+			req := new(Req)
+			if err := decode(req); err != nil {
+				return nil, err
+			}
+			return fn(ctx, req)
+		},
+		reqType:  reflect.TypeFor[*Req](),
+		respType: reflect.TypeFor[*Resp](),
+	})
+}
+
+func (s *Server) registerHandler(service, method string, mh *methodHandler) {
+	s.mu.Lock()
+	if ps, ok := s.services[service]; ok {
+		ps.handlers[method] = mh
+		s.mu.Unlock()
+		return
+	}
+
+	svc := &handlerService{name: service}
+	s.services[service] = &ProxyStub{
+		service:     svc,
+		handlers:    map[string]*methodHandler{method: mh},
+		serializers: s.serializers,
+	}
+	s.health.setIfAbsent(service, HealthServing)
+	s.mu.Unlock()
+
+	// 注册失败时服务仍然可以直接通过地址调用
+	_ = s.registerService(svc)
+}
+
+// RemoveHandler 移除通过 RegisterHandler 注册的方法，返回方法是否存在。
+//
+// 服务结构体上的同名方法在移除后重新生效；服务只有 handler 并且最后一个 handler 被移除时，
+// 服务同时被移除，开启了自动注册时从注册中心注销服务实例。
+func (s *Server) RemoveHandler(service, method string) bool {
+	s.mu.Lock()
+	ps, ok := s.services[service]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+	if _, ok = ps.handlers[method]; !ok {
+		s.mu.Unlock()
+		return false
+	}
+	delete(ps.handlers, method)
+
+	if _, ok = ps.service.(*handlerService); !ok || len(ps.handlers) > 0 {
+		s.mu.Unlock()
+		return true
+	}
+
+	delete(s.services, service)
+	s.health.remove(service)
+	s.mu.Unlock()
+
+	_ = s.deregisterService(service)
+	return true
+}
@@ -0,0 +1,159 @@
+package easyrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// HealthServiceName 内置健康检查服务的服务名
+const HealthServiceName = "easyrpc.health"
+
+// internalServicePrefix 内置服务的服务名前缀，内置服务不会注册到注册中心
+const internalServicePrefix = "easyrpc."
+
+func isInternalService(name string) bool {
+	return strings.HasPrefix(name, internalServicePrefix)
+}
+
+// HealthStatus 健康状态。
+type HealthStatus uint8
+
+const (
+	HealthUnknown HealthStatus = iota
+	HealthServing
+	HealthNotServing
+)
+
+var healthStatusNames = map[HealthStatus]string{
+	HealthUnknown:    "unknown",
+	HealthServing:    "serving",
+	HealthNotServing: "not serving",
+}
+
+func (s HealthStatus) String() string {
+	if name, ok := healthStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("health(%d)", uint8(s))
+}
+
+type HealthCheckReq struct {
+	// 服务名，为空时查询服务端整体的健康状态
+	Service string
+}
+
+type HealthCheckResp struct {
+	Status HealthStatus
+}
+
+var _ Service = (*healthService)(nil)
+
+// healthService 内置的健康检查服务，每个 Server 默认注册。
+//
+// 服务端整体（服务名为空）以及每个注册的服务默认都是 HealthServing，
+// 应用通过 Server.SetServingStatus 修改，Shutdown 时全部变为 HealthNotServing。
+type healthService struct {
+	mu       sync.RWMutex
+	statuses map[string]HealthStatus
+}
+
+func (h *healthService) Name() string {
+	return HealthServiceName
+}
+
+func (h *healthService) Check(_ context.Context, req *HealthCheckReq) (*HealthCheckResp, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status, ok := h.statuses[req.Service]
+	if !ok {
+		return nil, Errorf(CodeNotFound, "[easy-rpc] unknown service %s", req.Service)
+	}
+	return &HealthCheckResp{Status: status}, nil
+}
+
+// setIfAbsent 设置服务的初始状态。
+func (h *healthService) setIfAbsent(service string, status HealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.statuses[service]; !ok {
+		h.statuses[service] = status
+	}
+}
+
+// set 设置服务的状态，返回状态是否发生了变化。
+func (h *healthService) set(service string, status HealthStatus) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	old, ok := h.statuses[service]
+	h.statuses[service] = status
+	return !ok || old != status
+}
+
+// remove 移除服务的状态，服务被移除后查询返回 CodeNotFound。
+func (h *healthService) remove(service string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.statuses, service)
+}
+
+func (h *healthService) setAll(status HealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for service := range h.statuses {
+		h.statuses[service] = status
+	}
+}
+
+// serving 判断服务是否可用，服务端整体不可用时所有服务都不可用。
+func (h *healthService) serving(service string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.statuses[""] == HealthServing && h.statuses[service] == HealthServing
+}
+
+func newHealthService() *healthService {
+	return &healthService{
+		statuses: map[string]HealthStatus{"": HealthServing},
+	}
+}
+
+// SetServingStatus 设置服务的健康状态，service 为空时设置服务端整体的健康状态。
+//
+// 开启了 WithHealthWatchdog 时，状态变化会同步到注册中心，返回的是同步注册中心的错误。
+func (s *Server) SetServingStatus(service string, status HealthStatus) error {
+	if !s.health.set(service, status) {
+		return nil
+	}
+
+	// 同步注册中心期间不持有 s.mu，避免阻塞请求处理
+	s.regMu.Lock()
+	defer s.regMu.Unlock()
+
+	if s.addr == "" || s.regClosed {
+		// 启动时会按照当前的健康状态注册，关闭后不再同步
+		return nil
+	}
+
+	ctx, cancel := registryTimeoutContext()
+	defer cancel()
+	return s.syncHealth(ctx)
+}
+
+// CheckHealth 查询服务端的健康状态，service 为空时查询服务端整体的健康状态。
+//
+// 健康检查请求固定使用 json 序列化，不受 ClientBuilder.Serializer 的影响。
+func (c *Client) CheckHealth(ctx context.Context, service string) (HealthStatus, error) {
+	resp := &HealthCheckResp{}
+	if err := c.callBuiltin(ctx, HealthServiceName, "Check", &HealthCheckReq{Service: service}, resp); err != nil {
+		return HealthUnknown, err
+	}
+	return resp.Status, nil
+}
@@ -0,0 +1,169 @@
+package easyrpc
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/message"
+)
+
+const (
+	latencyWindowSize       = 128
+	latencyWindowMinSamples = 16
+)
+
+// HedgingPolicy 对冲请求策略。
+//
+// 首个请求在等待时间内没有返回时，再发送一份相同的请求，使用最先成功的响应并取消其余请求。
+// 对冲请求会被重复执行，因此只对幂等方法生效；同时配置了重试策略时，优先使用对冲策略。
+type HedgingPolicy struct {
+	// 最多发出的请求数（包含首个请求），小于等于 1 时不对冲
+	MaxAttempts int
+
+	// 发送下一个对冲请求前的等待时间
+	Delay time.Duration
+	// 按历史延迟的分位数计算等待时间，取值 (0, 1)，例如 0.95 表示 p95。
+	// 设置后优先于 Delay，历史样本不足时仍使用 Delay。
+	Percentile float64
+
+	// 不会结束对冲的状态码，服务端返回这些状态码时继续等待其余请求。
+	// 客户端本地的网络错误总是不会结束对冲。
+	NonFatalCodes []Code
+}
+
+// latencyWindow 记录最近的调用延迟，用于计算分位数。
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) record(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// percentile 计算延迟分位数，样本不足时返回 false。
+func (w *latencyWindow) percentile(p float64) (time.Duration, bool) {
+	w.mu.Lock()
+	if len(w.samples) < latencyWindowMinSamples {
+		w.mu.Unlock()
+		return 0, false
+	}
+	sorted := slices.Clone(w.samples)
+	w.mu.Unlock()
+
+	slices.Sort(sorted)
+	index := int(float64(len(sorted)-1) * p)
+	return sorted[index], true
+}
+
+// hedgingPolicy 按 method -> service 的顺序查找对冲策略。
+func (c *Client) hedgingPolicy(service, method string) (HedgingPolicy, bool) {
+	if policy, ok := c.hedgingPolicies[methodKey(service, method)]; ok {
+		return policy, true
+	}
+	policy, ok := c.hedgingPolicies[service]
+	return policy, ok
+}
+
+func (c *Client) hedgingDelay(key string, policy HedgingPolicy) time.Duration {
+	if policy.Percentile > 0 && policy.Percentile < 1 {
+		if val, ok := c.latencies.Load(key); ok {
+			if delay, ok := val.(*latencyWindow).percentile(policy.Percentile); ok {
+				return delay
+			}
+		}
+	}
+	return policy.Delay
+}
+
+func (c *Client) recordLatency(key string, latency time.Duration) {
+	val, _ := c.latencies.LoadOrStore(key, &latencyWindow{})
+	val.(*latencyWindow).record(latency)
+}
+
+// callWithHedging 按对冲策略发起调用。
+func (c *Client) callWithHedging(ctx context.Context, req *message.Req, policy HedgingPolicy) (*message.Resp, error) {
+	type result struct {
+		resp *message.Resp
+		err  error
+	}
+
+	// 返回时取消其余仍在进行中的请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	key := methodKey(req.Service, req.Method)
+	delay := c.hedgingDelay(key, policy)
+	sampled := policy.Percentile > 0 && policy.Percentile < 1
+
+	// 带缓冲，被取消的请求返回时不会阻塞
+	results := make(chan result, policy.MaxAttempts)
+	send := func(attempt int) {
+		// 每个对冲请求都需要独立的 meta
+		hedged := withAttempt(req, attempt)
+
+		go func() {
+			start := time.Now()
+			resp, err := c.sendRequest(ctx, hedged)
+			// 所有收到响应的请求都计入延迟，不论是否最先返回；被取消的请求至少耗时这么久，同样计入。
+			// 只统计最先返回的请求会使分位数偏低，等待时间越来越短，最终几乎每次调用都会对冲。
+			if sampled && (err == nil || ctx.Err() != nil) {
+				c.recordLatency(key, time.Since(start))
+			}
+			results <- result{resp: resp, err: err}
+		}()
+	}
+
+	send(1)
+	sent, inflight := 1, 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var last result
+	for inflight > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			if sent < policy.MaxAttempts {
+				sent++
+				inflight++
+				send(sent)
+				timer.Reset(delay)
+			}
+		case res := <-results:
+			inflight--
+			if res.err == nil {
+				code := Code(res.resp.Status)
+				if code == CodeOK {
+					return res.resp, nil
+				}
+				if !slices.Contains(policy.NonFatalCodes, code) {
+					return res.resp, nil
+				}
+			}
+			last = res
+
+			// 请求失败时不再等待，直接发送下一个对冲请求
+			if sent < policy.MaxAttempts {
+				sent++
+				inflight++
+				send(sent)
+				timer.Reset(delay)
+			}
+		}
+	}
+	return last.resp, last.err
+}
@@ -0,0 +1,105 @@
+package easyrpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newLatencyWindow 依次记录 1ms 到 n ms 的延迟。
+func newLatencyWindow(n int) *latencyWindow {
+	w := &latencyWindow{}
+	for i := 1; i <= n; i++ {
+		w.record(time.Duration(i) * time.Millisecond)
+	}
+	return w
+}
+
+func TestLatencyWindow(t *testing.T) {
+	tcs := []struct {
+		name    string
+		samples int
+		p       float64
+		want    time.Duration
+		wantOk  bool
+	}{
+		{
+			name:    "not enough samples",
+			samples: latencyWindowMinSamples - 1,
+			p:       0.5,
+		}, {
+			name:    "p50",
+			samples: 100,
+			p:       0.5,
+			want:    50 * time.Millisecond,
+			wantOk:  true,
+		}, {
+			name:    "p95",
+			samples: 100,
+			p:       0.95,
+			want:    95 * time.Millisecond,
+			wantOk:  true,
+		}, {
+			// 超过窗口大小后只保留最近的样本
+			name:    "window full",
+			samples: latencyWindowSize + 72,
+			p:       0,
+			want:    73 * time.Millisecond,
+			wantOk:  true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := newLatencyWindow(tc.samples).percentile(tc.p)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHedgingDelay(t *testing.T) {
+	tcs := []struct {
+		name    string
+		samples int
+		policy  HedgingPolicy
+		want    time.Duration
+	}{
+		{
+			name:    "fixed delay",
+			samples: 100,
+			policy:  HedgingPolicy{Delay: 10 * time.Millisecond},
+			want:    10 * time.Millisecond,
+		}, {
+			name:   "no samples",
+			policy: HedgingPolicy{Delay: 10 * time.Millisecond, Percentile: 0.9},
+			want:   10 * time.Millisecond,
+		}, {
+			name:    "not enough samples",
+			samples: latencyWindowMinSamples - 1,
+			policy:  HedgingPolicy{Delay: 10 * time.Millisecond, Percentile: 0.9},
+			want:    10 * time.Millisecond,
+		}, {
+			name:    "percentile",
+			samples: 100,
+			policy:  HedgingPolicy{Delay: 10 * time.Millisecond, Percentile: 0.9},
+			want:    90 * time.Millisecond,
+		}, {
+			name:    "invalid percentile",
+			samples: 100,
+			policy:  HedgingPolicy{Delay: 10 * time.Millisecond, Percentile: 1},
+			want:    10 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Client{}
+			if tc.samples > 0 {
+				c.latencies.Store("user-service.SayHello", newLatencyWindow(tc.samples))
+			}
+			assert.Equal(t, tc.want, c.hedgingDelay("user-service.SayHello", tc.policy))
+		})
+	}
+}
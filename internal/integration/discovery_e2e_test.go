@@ -0,0 +1,389 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/breaker"
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoveryRemoteCall(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8084"),
+	)
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8084")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "test-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder().
+		Registry(r, "test-service").
+		Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	cs := &testClientService{}
+	client.InitService(cs)
+
+	resp, err := cs.SayHello(ctx, &testReq{Name: "jrmarcco"})
+	require.NoError(t, err)
+	require.Equal(t, "hello jrmarcco", resp.Msg)
+
+	// draining 的实例不再接收新的请求
+	instances, err := r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	require.NoError(t, r.UpdateStatus(ctx, instances[0], registry.StatusDraining))
+	require.Eventually(t, func() bool {
+		_, err = cs.SayHello(ctx, &testReq{Name: "jrmarcco"})
+		return easyrpc.CodeOf(err) == easyrpc.CodeUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, r.UpdateStatus(ctx, instances[0], registry.StatusServing))
+	require.Eventually(t, func() bool {
+		_, err = cs.SayHello(ctx, &testReq{Name: "jrmarcco"})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, svr.Shutdown(shutdownCtx))
+
+	// 服务端退出时已经从注册中心注销
+	instances, err = r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	require.Empty(t, instances)
+
+	_, err = cs.SayHello(ctx, &testReq{Name: "jrmarcco"})
+	require.Equal(t, easyrpc.CodeUnavailable, easyrpc.CodeOf(err))
+}
+
+// blockingRegistry 在 block 和 unblock 之间阻塞所有写操作，模拟响应缓慢的注册中心。
+type blockingRegistry struct {
+	*memory.Registry
+
+	mu      sync.Mutex
+	release chan struct{}
+}
+
+func (r *blockingRegistry) block() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.release = make(chan struct{})
+}
+
+func (r *blockingRegistry) unblock() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	close(r.release)
+	r.release = nil
+}
+
+func (r *blockingRegistry) wait(ctx context.Context) error {
+	r.mu.Lock()
+	ch := r.release
+	r.mu.Unlock()
+
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *blockingRegistry) Register(ctx context.Context, instance registry.ServiceInstance) error {
+	if err := r.wait(ctx); err != nil {
+		return err
+	}
+	return r.Registry.Register(ctx, instance)
+}
+
+func (r *blockingRegistry) Unregister(ctx context.Context, instance registry.ServiceInstance) error {
+	if err := r.wait(ctx); err != nil {
+		return err
+	}
+	return r.Registry.Unregister(ctx, instance)
+}
+
+func (r *blockingRegistry) UpdateStatus(ctx context.Context, instance registry.ServiceInstance, status registry.Status) error {
+	if err := r.wait(ctx); err != nil {
+		return err
+	}
+	return r.Registry.UpdateStatus(ctx, instance, status)
+}
+
+// callWhileBlocked 在注册中心阻塞期间执行 op，同时确认请求处理不受影响。
+func callWhileBlocked(t *testing.T, r *blockingRegistry, client *easyrpc.Client, op func()) {
+	t.Helper()
+
+	r.block()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		op()
+	}()
+	// 等待 op 进入注册中心调用
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	resp := &testResp{}
+	err := client.Invoke(ctx, "test-service", "SayHello", &testReq{Name: "jrmarcco"}, resp)
+	r.unblock()
+	<-done
+
+	require.NoError(t, err)
+	require.Equal(t, "hello jrmarcco", resp.Msg)
+}
+
+func TestSlowRegistryDoesNotBlockRequests(t *testing.T) {
+	r := &blockingRegistry{Registry: memory.NewRegistry()}
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8093"),
+	)
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8093")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "test-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8093").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	// 启动之后注册的服务在注册中心阻塞期间不影响已有服务处理请求
+	callWhileBlocked(t, r, client, func() {
+		svr.RegisterService(&retryServerService{})
+	})
+
+	instances, err := r.ListServices(ctx, "retry-service")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+}
+
+// subscribeRegistry 记录客户端订阅时使用的 ctx，err 不为空时 ListServices 返回 err。
+type subscribeRegistry struct {
+	*memory.Registry
+
+	err  error
+	subs []context.Context
+}
+
+func (r *subscribeRegistry) ListServices(ctx context.Context, serviceName string) ([]registry.ServiceInstance, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.Registry.ListServices(ctx, serviceName)
+}
+
+func (r *subscribeRegistry) Subscribe(ctx context.Context, serviceName string) <-chan registry.Event {
+	r.subs = append(r.subs, ctx)
+	return r.Registry.Subscribe(ctx, serviceName)
+}
+
+func TestDiscoveryUnsubscribe(t *testing.T) {
+	r := &subscribeRegistry{Registry: memory.NewRegistry(), err: errors.New("unavailable")}
+	defer func() { _ = r.Close() }()
+
+	// 拉取实例失败时取消订阅
+	_, err := easyrpc.NewClientBuilder().Registry(r, "test-service").Build()
+	require.Error(t, err)
+	require.Len(t, r.subs, 1)
+	require.Error(t, r.subs[0].Err())
+
+	// 客户端关闭时取消订阅
+	r.err = nil
+	client, err := easyrpc.NewClientBuilder().Registry(r, "test-service").Build()
+	require.NoError(t, err)
+	require.Len(t, r.subs, 2)
+	require.NoError(t, r.subs[1].Err())
+
+	require.NoError(t, client.Close())
+	require.Error(t, r.subs[1].Err())
+}
+
+func TestDiscoveryResetsBreaker(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	ss := &countingServerService{}
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8097"),
+	)
+	svr.RegisterService(ss)
+
+	go func() {
+		err := svr.Start(":8097")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	var instances []registry.ServiceInstance
+	require.Eventually(t, func() bool {
+		var err error
+		instances, err = r.ListServices(ctx, "retry-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cfg := breaker.DefaultConfig()
+	cfg.ConsecutiveFailures = 1
+	cfg.OpenTimeout = time.Minute
+
+	client, err := easyrpc.NewClientBuilder().
+		Registry(r, "retry-service").
+		CircuitBreaker(cfg).
+		Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	cs := &retryClientService{}
+	client.InitService(cs)
+
+	_, err = cs.Flaky(ctx, &testReq{Name: "jrmarcco"})
+	require.Equal(t, easyrpc.CodeUnavailable, easyrpc.CodeOf(err))
+	_, err = cs.Flaky(ctx, &testReq{Name: "jrmarcco"})
+	require.ErrorIs(t, err, breaker.ErrOpen)
+	require.Equal(t, int32(1), ss.calls.Load())
+
+	// 节点下线后熔断器被移除，重新上线时从 closed 状态开始
+	require.NoError(t, r.Unregister(ctx, instances[0]))
+	require.Eventually(t, func() bool {
+		_, err = cs.Flaky(ctx, &testReq{Name: "jrmarcco"})
+		return err != nil && !errors.Is(err, breaker.ErrOpen)
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, r.Register(ctx, instances[0]))
+	require.Eventually(t, func() bool {
+		_, _ = cs.Flaky(ctx, &testReq{Name: "jrmarcco"})
+		return ss.calls.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+type groupServerService struct {
+	group string
+}
+
+func (ss *groupServerService) Name() string {
+	return "group-service"
+}
+
+func (ss *groupServerService) SayHello(ctx context.Context, req *testReq) (*testResp, error) {
+	// 同时返回服务端收到的分组，验证分组随请求传递
+	return &testResp{Msg: ss.group + "/" + easyrpc.GroupFromContext(ctx)}, nil
+}
+
+func TestDiscoveryGroup(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	start := func(group, addr string) *easyrpc.Server {
+		svr := easyrpc.NewServer(
+			easyrpc.WithRegistry(r),
+			easyrpc.WithAdvertiseAddr("127.0.0.1"+addr),
+			easyrpc.WithServiceInstance(registry.ServiceInstance{Group: group}),
+		)
+		name := group
+		if name == "" {
+			name = registry.DefaultGroup
+		}
+		svr.RegisterService(&groupServerService{group: name})
+
+		go func() {
+			err := svr.Start(addr)
+			require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+		}()
+		t.Cleanup(func() { _ = svr.Shutdown(context.Background()) })
+		return svr
+	}
+	start("", ":8102")
+	canary := start("canary", ":8103")
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "group-service")
+		return err == nil && len(instances) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder().
+		Registry(r, "group-service").
+		Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	sayHello := func(ctx context.Context) string {
+		resp := &testResp{}
+		require.NoError(t, client.Invoke(ctx, "group-service", "SayHello", &testReq{}, resp))
+		return resp.Msg
+	}
+
+	tcs := []struct {
+		name  string
+		group string
+		want  string
+	}{
+		{name: "default group", want: "default/default"},
+		{name: "canary group", group: "canary", want: "canary/canary"},
+		// 分组没有实例时回退到默认分组
+		{name: "empty group", group: "gray", want: "default/gray"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			callCtx := ctx
+			if tc.group != "" {
+				callCtx = easyrpc.ContextWithGroup(ctx, tc.group)
+			}
+			// 多次调用都路由到同一分组
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, tc.want, sayHello(callCtx))
+			}
+		})
+	}
+
+	// 分组的实例全部下线后回退到默认分组
+	require.NoError(t, canary.Shutdown(ctx))
+	canaryCtx := easyrpc.ContextWithGroup(ctx, "canary")
+	require.Eventually(t, func() bool {
+		resp := &testResp{}
+		err := client.Invoke(canaryCtx, "group-service", "SayHello", &testReq{}, resp)
+		return err == nil && resp.Msg == "default/canary"
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,157 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8089"),
+		easyrpc.WithReflection(),
+	)
+	svr.RegisterService(&testServerService{})
+	easyrpc.Handle(svr, "greeter-service", "SayHello", func(_ context.Context, req *testReq) (*testResp, error) {
+		return &testResp{Msg: "hi " + req.Name}, nil
+	})
+
+	go func() {
+		err := svr.Start(":8089")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8089").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	sayHello := func(service string) (string, error) {
+		resp := &testResp{}
+		err := client.Invoke(ctx, service, "SayHello", &testReq{Name: "jrmarcco"}, resp)
+		return resp.Msg, err
+	}
+
+	msg, err := sayHello("greeter-service")
+	require.NoError(t, err)
+	assert.Equal(t, "hi jrmarcco", msg)
+
+	instances, err := r.ListServices(ctx, "greeter-service")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	// 启动之后注册的服务同样注册到注册中心
+	svr.RegisterHandler("echo-service", "Echo", func(_ context.Context, decode func(in any) error) (any, error) {
+		var in map[string]any
+		if err := decode(&in); err != nil {
+			return nil, err
+		}
+		return in, nil
+	})
+	resp, err := client.InvokeJSON(ctx, "echo-service", "Echo", json.RawMessage(`{"Name": "jrmarcco"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Name": "jrmarcco"}`, string(resp))
+
+	instances, err = r.ListServices(ctx, "echo-service")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	// 请求体无法反序列化
+	_, err = client.InvokeJSON(ctx, "echo-service", "Echo", json.RawMessage(`[1]`))
+	assert.Equal(t, easyrpc.CodeInvalidArgument, easyrpc.CodeOf(err))
+
+	// handler 覆盖服务结构体上的同名方法，移除后恢复
+	easyrpc.Handle(svr, "test-service", "SayHello", func(_ context.Context, req *testReq) (*testResp, error) {
+		return &testResp{Msg: "overridden " + req.Name}, nil
+	})
+	msg, err = sayHello("test-service")
+	require.NoError(t, err)
+	assert.Equal(t, "overridden jrmarcco", msg)
+
+	services, err := client.Reflect(ctx, "greeter-service")
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Len(t, services[0].Methods, 1)
+	assert.Equal(t, "SayHello", services[0].Methods[0].Name)
+	assert.Equal(t, "github.com/JrMarcco/easy-rpc/internal/integration.testReq", services[0].Methods[0].Request.GoType)
+
+	assert.True(t, svr.RemoveHandler("test-service", "SayHello"))
+	assert.False(t, svr.RemoveHandler("test-service", "SayHello"))
+	msg, err = sayHello("test-service")
+	require.NoError(t, err)
+	assert.Equal(t, "hello jrmarcco", msg)
+
+	// 移除服务的最后一个 handler 时服务同时被移除
+	assert.True(t, svr.RemoveHandler("greeter-service", "SayHello"))
+	_, err = sayHello("greeter-service")
+	assert.Equal(t, easyrpc.CodeNotFound, easyrpc.CodeOf(err))
+
+	_, err = client.CheckHealth(ctx, "greeter-service")
+	assert.Equal(t, easyrpc.CodeNotFound, easyrpc.CodeOf(err))
+
+	instances, err = r.ListServices(ctx, "greeter-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	instances, err = r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+}
+
+func TestHandlerRegistryDoesNotBlockRequests(t *testing.T) {
+	r := &blockingRegistry{Registry: memory.NewRegistry()}
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8095"),
+	)
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8095")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "test-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8095").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	// 注册和移除 handler 时访问注册中心不影响请求处理
+	callWhileBlocked(t, r, client, func() {
+		easyrpc.Handle(svr, "greeter-service", "SayHello", func(_ context.Context, req *testReq) (*testResp, error) {
+			return &testResp{Msg: "hi " + req.Name}, nil
+		})
+	})
+	instances, err := r.ListServices(ctx, "greeter-service")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	callWhileBlocked(t, r, client, func() {
+		assert.True(t, svr.RemoveHandler("greeter-service", "SayHello"))
+	})
+	instances, err = r.ListServices(ctx, "greeter-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
@@ -0,0 +1,162 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheck(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8085"),
+		easyrpc.WithHealthWatchdog(easyrpc.WatchdogMarkUnhealthy),
+	)
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8085")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "test-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// 内置服务不会注册到注册中心
+	instances, err := r.ListServices(ctx, easyrpc.HealthServiceName)
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	client, err := easyrpc.NewClientBuilder(":8085").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	tcs := []struct {
+		name     string
+		service  string
+		status   easyrpc.HealthStatus
+		wantCode easyrpc.Code
+	}{
+		{name: "server", service: "", status: easyrpc.HealthServing},
+		{name: "service", service: "test-service", status: easyrpc.HealthServing},
+		{name: "unknown service", service: "unknown-service", wantCode: easyrpc.CodeNotFound},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			status, err := client.CheckHealth(ctx, tc.service)
+			if tc.wantCode != easyrpc.CodeOK {
+				assert.Equal(t, tc.wantCode, easyrpc.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, status)
+		})
+	}
+
+	// 服务不可用时注册中心中的实例被标记为 unhealthy
+	require.NoError(t, svr.SetServingStatus("test-service", easyrpc.HealthNotServing))
+
+	status, err := client.CheckHealth(ctx, "test-service")
+	require.NoError(t, err)
+	assert.Equal(t, easyrpc.HealthNotServing, status)
+
+	instances, err = r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, registry.StatusUnhealthy, instances[0].Status)
+
+	require.NoError(t, svr.SetServingStatus("test-service", easyrpc.HealthServing))
+	instances, err = r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, registry.StatusServing, instances[0].Status)
+}
+
+func TestHealthWatchdogDeregister(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8086"),
+		easyrpc.WithHealthWatchdog(easyrpc.WatchdogDeregister),
+	)
+	svr.RegisterService(&testServerService{})
+	// 启动前设置为不可用，启动时不会注册
+	require.NoError(t, svr.SetServingStatus("", easyrpc.HealthNotServing))
+
+	go func() {
+		err := svr.Start(":8086")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx := context.Background()
+	instances, err := r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	require.NoError(t, svr.SetServingStatus("", easyrpc.HealthServing))
+	instances, err = r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	assert.Len(t, instances, 1)
+
+	require.NoError(t, svr.SetServingStatus("test-service", easyrpc.HealthNotServing))
+	instances, err = r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestHealthSyncDoesNotBlockRequests(t *testing.T) {
+	r := &blockingRegistry{Registry: memory.NewRegistry()}
+	defer func() { _ = r.Close() }()
+
+	svr := easyrpc.NewServer(
+		easyrpc.WithRegistry(r),
+		easyrpc.WithAdvertiseAddr("127.0.0.1:8094"),
+		easyrpc.WithHealthWatchdog(easyrpc.WatchdogMarkUnhealthy),
+	)
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8094")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+
+	ctx := context.Background()
+	require.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "test-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8094").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	// 同步注册中心期间不影响请求处理
+	callWhileBlocked(t, r, client, func() {
+		assert.NoError(t, svr.SetServingStatus("test-service", easyrpc.HealthNotServing))
+	})
+
+	instances, err := r.ListServices(ctx, "test-service")
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, registry.StatusUnhealthy, instances[0].Status)
+}
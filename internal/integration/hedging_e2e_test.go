@@ -0,0 +1,67 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/stretchr/testify/require"
+)
+
+type hedgingClientService struct {
+	Slow func(ctx context.Context, req *testReq) (*testResp, error)
+}
+
+func (cs *hedgingClientService) Name() string {
+	return "hedging-service"
+}
+
+type hedgingServerService struct{}
+
+func (ss *hedgingServerService) Name() string {
+	return "hedging-service"
+}
+
+// Slow 首个请求很慢，对冲请求立即返回。
+func (ss *hedgingServerService) Slow(ctx context.Context, req *testReq) (*testResp, error) {
+	attempt := easyrpc.AttemptFromContext(ctx)
+	if attempt == 1 {
+		time.Sleep(time.Second)
+	}
+	return &testResp{
+		Msg: fmt.Sprintf("hello %s at attempt %d", req.Name, attempt),
+	}, nil
+}
+
+func TestHedgingRemoteCall(t *testing.T) {
+	svr := easyrpc.NewServer()
+	svr.RegisterService(&hedgingServerService{})
+
+	go func() {
+		err := svr.Start(":8083")
+		require.NoError(t, err)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8083").
+		Idempotent("hedging-service").
+		ServiceHedgingPolicy("hedging-service", easyrpc.HedgingPolicy{
+			MaxAttempts: 2,
+			Delay:       20 * time.Millisecond,
+		}).
+		Build()
+	require.NoError(t, err)
+
+	cs := &hedgingClientService{}
+	client.InitService(cs)
+
+	start := time.Now()
+	resp, err := cs.Slow(context.Background(), &testReq{Name: "jrmarcco"})
+	require.NoError(t, err)
+	require.Equal(t, "hello jrmarcco at attempt 2", resp.Msg)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
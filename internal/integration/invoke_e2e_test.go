@@ -0,0 +1,137 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/compress/gzip"
+	"github.com/JrMarcco/easy-rpc/internal/integration/pb"
+	"github.com/JrMarcco/easy-rpc/serialize/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoke(t *testing.T) {
+	svr := easyrpc.NewServer()
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8088")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8088").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+
+	t.Run("typed", func(t *testing.T) {
+		resp := &testResp{}
+		require.NoError(t, client.Invoke(ctx, "test-service", "SayHello", &testReq{Name: "jrmarcco"}, resp))
+		assert.Equal(t, "hello jrmarcco", resp.Msg)
+	})
+
+	t.Run("call options", func(t *testing.T) {
+		resp := &pb.TestResp{}
+		err := client.Invoke(ctx, "test-service", "SayHelloProto", &pb.TestReq{Name: "jrmarcco"}, resp,
+			easyrpc.WithCallSerializer(&proto.Serializer{}),
+			easyrpc.WithCallCompressor(&gzip.Compressor{}),
+			easyrpc.WithCallMeta("trace-id", "abc"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "hello jrmarcco", resp.Msg)
+	})
+
+	t.Run("unary", func(t *testing.T) {
+		sayHello := easyrpc.Unary[testReq, testResp](client, "test-service", "SayHello")
+		resp, err := sayHello(ctx, &testReq{Name: "jrmarcco"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello jrmarcco", resp.Msg)
+
+		sayHelloProto := easyrpc.Unary[pb.TestReq, pb.TestResp](client, "test-service", "SayHelloProto",
+			easyrpc.WithCallSerializer(&proto.Serializer{}),
+		)
+		protoResp, err := sayHelloProto(ctx, &pb.TestReq{Name: "jrmarcco"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello jrmarcco", protoResp.Msg)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		resp, err := client.InvokeJSON(ctx, "test-service", "SayHello", json.RawMessage(`{"Name": "jrmarcco"}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"Msg": "hello jrmarcco"}`, string(resp))
+
+		_, err = client.InvokeJSON(ctx, "test-service", "SayHello", json.RawMessage(`{"Name": `))
+		assert.Equal(t, easyrpc.CodeInvalidArgument, easyrpc.CodeOf(err))
+	})
+
+	t.Run("map", func(t *testing.T) {
+		resp, err := client.InvokeMap(ctx, "test-service", "SayHello", map[string]any{"Name": "jrmarcco"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"Msg": "hello jrmarcco"}, resp)
+	})
+
+	t.Run("shared options", func(t *testing.T) {
+		// 多个协程共用有剩余容量的切片，调用不能写入切片
+		opts := make([]easyrpc.CallOption, 1, 4)
+		opts[0] = easyrpc.WithCallTimeout(time.Second)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.InvokeJSON(ctx, "test-service", "SayHello", json.RawMessage(`{"Name": "jrmarcco"}`), opts...)
+				assert.NoError(t, err)
+				_, err = client.InvokeMap(ctx, "test-service", "SayHello", map[string]any{"Name": "jrmarcco"}, opts...)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Nil(t, opts[:2][1])
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := client.InvokeMap(ctx, "test-service", "Unknown", nil)
+		assert.Equal(t, easyrpc.CodeNotFound, easyrpc.CodeOf(err))
+	})
+}
+
+func TestInvokeAfterClose(t *testing.T) {
+	svr := easyrpc.NewServer()
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8098")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	policy := easyrpc.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	client, err := easyrpc.NewClientBuilder(":8098").
+		RetryPolicy(policy).
+		Idempotent("test-service").
+		Build()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resp := &testResp{}
+	require.NoError(t, client.Invoke(ctx, "test-service", "SayHello", &testReq{Name: "jrmarcco"}, resp))
+
+	// 关闭之后的调用直接失败，不会重新创建连接池
+	require.NoError(t, client.Close())
+	err = client.Invoke(ctx, "test-service", "SayHello", &testReq{Name: "jrmarcco"}, resp)
+	assert.ErrorIs(t, err, easyrpc.ErrClientClosed)
+}
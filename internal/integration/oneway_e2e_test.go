@@ -0,0 +1,106 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLimiter 记录进行中的请求数的并发限制器。
+type countingLimiter struct {
+	inflight atomic.Int32
+}
+
+func (l *countingLimiter) Acquire() (func(), bool) {
+	l.inflight.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.inflight.Add(-1) })
+	}, true
+}
+
+type onewayServerService struct {
+	started  chan struct{}
+	release  chan struct{}
+	finished atomic.Bool
+	ctxErr   atomic.Value
+}
+
+func (ss *onewayServerService) Name() string {
+	return "oneway-service"
+}
+
+func (ss *onewayServerService) Notify(ctx context.Context, _ *testReq) (*testResp, error) {
+	close(ss.started)
+	<-ss.release
+	if err := ctx.Err(); err != nil {
+		ss.ctxErr.Store(err)
+	}
+	ss.finished.Store(true)
+	return &testResp{}, nil
+}
+
+func TestOnewayTracked(t *testing.T) {
+	limiter := &countingLimiter{}
+	ss := &onewayServerService{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	svr := easyrpc.NewServer(easyrpc.WithConcurrencyLimiter(limiter))
+	svr.RegisterService(ss)
+
+	go func() {
+		err := svr.Start(":8101")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8101").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = client.Invoke(ctx, "oneway-service", "Notify", &testReq{Name: "jrmarcco"}, &testResp{}, easyrpc.WithCallOneway())
+	require.NoError(t, err)
+
+	// 处理中的 oneway 调用占用并发许可
+	select {
+	case <-ss.started:
+	case <-time.After(time.Second):
+		require.FailNow(t, "oneway call not received")
+	}
+	assert.Equal(t, int32(1), limiter.inflight.Load())
+
+	// Shutdown 等待 oneway 调用处理完成
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- svr.Shutdown(context.Background())
+	}()
+	select {
+	case <-shutdown:
+		require.FailNow(t, "shutdown returned before the oneway call finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(ss.release)
+	select {
+	case err = <-shutdown:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "shutdown did not return")
+	}
+	assert.True(t, ss.finished.Load())
+	assert.Equal(t, int32(0), limiter.inflight.Load())
+	// 请求返回后 oneway 调用的 ctx 不会被取消
+	assert.Nil(t, ss.ctxErr.Load())
+}
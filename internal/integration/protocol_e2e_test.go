@@ -0,0 +1,61 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/serialize/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolVersion(t *testing.T) {
+	svr := easyrpc.NewServer()
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8099")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := net.Dial("tcp", "127.0.0.1:8099")
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	call := func(version uint8) []byte {
+		req := &message.Req{
+			Version:    version,
+			Serializer: (&json.Serializer{}).Code(),
+			Service:    "test-service",
+			Method:     "Unknown",
+			Body:       []byte(`{}`),
+		}
+		req.SetLength()
+		_, err := conn.Write(message.EncodeReq(req))
+		require.NoError(t, err)
+
+		data, err := easyrpc.ReadMsg(conn)
+		require.NoError(t, err)
+		return data
+	}
+
+	// 旧版本的客户端收到的响应没有状态码，错误信息紧跟在 message id 之后
+	data := call(message.VersionLegacy)
+	headLen := binary.BigEndian.Uint32(data[:4])
+	assert.Contains(t, string(data[12:headLen]), "not found")
+
+	// 新版本的请求收到携带状态码的响应
+	resp := message.DecodeResp(call(message.VersionStatus))
+	assert.Equal(t, message.VersionStatus, resp.Version)
+	assert.Equal(t, easyrpc.CodeNotFound, easyrpc.Code(resp.Status))
+	assert.Contains(t, string(resp.Err), "not found")
+}
@@ -0,0 +1,56 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	svr := easyrpc.NewServer(
+		easyrpc.WithRateLimit(easyrpc.RateLimitRule{
+			CallerKey: easyrpc.MetaKeyCaller,
+			Limiter:   ratelimit.NewSlidingWindow(time.Minute, 2),
+		}),
+	)
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8100")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	newClient := func(caller string) *testClientService {
+		client, err := easyrpc.NewClientBuilder(":8100").Caller(caller).Build()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+
+		cs := &testClientService{}
+		client.InitService(cs)
+		return cs
+	}
+
+	ctx := context.Background()
+	req := &testReq{Name: "jrmarcco"}
+
+	cs := newClient("order-service")
+	for i := 0; i < 2; i++ {
+		_, err := cs.SayHello(ctx, req)
+		require.NoError(t, err)
+	}
+	_, err := cs.SayHello(ctx, req)
+	assert.Equal(t, easyrpc.CodeResourceExhausted, easyrpc.CodeOf(err))
+
+	// 不同调用方分别限流
+	_, err = newClient("pay-service").SayHello(ctx, req)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,110 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReflection(t *testing.T) {
+	svr := easyrpc.NewServer(easyrpc.WithReflection())
+	svr.RegisterService(&testServerService{})
+
+	go func() {
+		err := svr.Start(":8087")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8087").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+	services, err := client.Reflect(ctx, "")
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(services))
+	for _, service := range services {
+		names = append(names, service.Name)
+	}
+	assert.Equal(t, []string{easyrpc.HealthServiceName, easyrpc.ReflectionServiceName, "test-service"}, names)
+
+	services, err = client.Reflect(ctx, "test-service")
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+
+	methods := services[0].Methods
+	require.Len(t, methods, 2)
+
+	assert.Equal(t, "SayHello", methods[0].Name)
+	assert.Equal(t, "github.com/JrMarcco/easy-rpc/internal/integration.testReq", methods[0].Request.GoType)
+	assert.Equal(t, "github.com/JrMarcco/easy-rpc/internal/integration.testResp", methods[0].Response.GoType)
+	assert.Empty(t, methods[0].Request.ProtoName)
+
+	// proto 消息可以通过返回的文件描述重建
+	assert.Equal(t, "SayHelloProto", methods[1].Name)
+	assert.Equal(t, "proto.TestReq", methods[1].Request.ProtoName)
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, bs := range methods[1].Request.ProtoFiles {
+		fd := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, proto.Unmarshal(bs, fd))
+		fds.File = append(fds.File, fd)
+	}
+	files, err := protodesc.NewFiles(fds)
+	require.NoError(t, err)
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(methods[1].Request.ProtoName))
+	require.NoError(t, err)
+	assert.Equal(t, "TestReq", string(desc.Name()))
+
+	_, err = client.Reflect(ctx, "unknown-service")
+	assert.Equal(t, easyrpc.CodeNotFound, easyrpc.CodeOf(err))
+}
+
+func TestDispatchSafety(t *testing.T) {
+	svr := easyrpc.NewServer(easyrpc.WithReflection())
+	svr.RegisterService(&testServerService{})
+	easyrpc.Handle(svr, "panic-service", "Panic", func(context.Context, *testReq) (*testResp, error) {
+		panic("boom")
+	})
+
+	go func() {
+		err := svr.Start(":8091")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8091").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	ctx := context.Background()
+
+	// 签名不符合要求的导出方法不能被远程调用
+	for _, service := range []string{easyrpc.HealthServiceName, easyrpc.ReflectionServiceName, "test-service"} {
+		_, err = client.InvokeMap(ctx, service, "Name", nil)
+		assert.Equal(t, easyrpc.CodeNotFound, easyrpc.CodeOf(err), service)
+	}
+
+	// 业务方法 panic 时返回 CodeInternal，服务端继续正常处理请求
+	_, err = client.InvokeMap(ctx, "panic-service", "Panic", nil)
+	assert.Equal(t, easyrpc.CodeInternal, easyrpc.CodeOf(err))
+
+	status, err := client.CheckHealth(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, easyrpc.HealthServing, status)
+}
@@ -0,0 +1,184 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/breaker"
+	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/serialize/json"
+	"github.com/stretchr/testify/require"
+)
+
+type retryClientService struct {
+	Flaky func(ctx context.Context, req *testReq) (*testResp, error)
+}
+
+func (cs *retryClientService) Name() string {
+	return "retry-service"
+}
+
+type retryServerService struct {
+	failures int
+}
+
+func (ss *retryServerService) Name() string {
+	return "retry-service"
+}
+
+func (ss *retryServerService) Flaky(ctx context.Context, req *testReq) (*testResp, error) {
+	attempt := easyrpc.AttemptFromContext(ctx)
+	if attempt <= ss.failures {
+		return nil, easyrpc.Errorf(easyrpc.CodeUnavailable, "attempt %d failed", attempt)
+	}
+	return &testResp{
+		Msg: fmt.Sprintf("hello %s at attempt %d", req.Name, attempt),
+	}, nil
+}
+
+func TestRetryRemoteCall(t *testing.T) {
+	svr := easyrpc.NewServer()
+	svr.RegisterService(&retryServerService{failures: 2})
+
+	go func() {
+		err := svr.Start(":8082")
+		require.NoError(t, err)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	policy := easyrpc.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	tcs := []struct {
+		name       string
+		idempotent bool
+		wantMsg    string
+		wantCode   easyrpc.Code
+	}{
+		{
+			name:       "idempotent",
+			idempotent: true,
+			wantMsg:    "hello jrmarcco at attempt 3",
+		}, {
+			name:     "non idempotent",
+			wantCode: easyrpc.CodeUnavailable,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cb := easyrpc.NewClientBuilder(":8082").RetryPolicy(policy)
+			if tc.idempotent {
+				cb.Idempotent("retry-service", "Flaky")
+			}
+			client, err := cb.Build()
+			require.NoError(t, err)
+
+			cs := &retryClientService{}
+			client.InitService(cs)
+
+			resp, err := cs.Flaky(context.Background(), &testReq{Name: "jrmarcco"})
+			if tc.wantCode != easyrpc.CodeOK {
+				require.Equal(t, tc.wantCode, easyrpc.CodeOf(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMsg, resp.Msg)
+		})
+	}
+}
+
+type countingServerService struct {
+	calls atomic.Int32
+}
+
+func (ss *countingServerService) Name() string {
+	return "retry-service"
+}
+
+func (ss *countingServerService) Flaky(_ context.Context, _ *testReq) (*testResp, error) {
+	ss.calls.Add(1)
+	return nil, easyrpc.Errorf(easyrpc.CodeUnavailable, "unavailable")
+}
+
+func TestRetryBreakerOpen(t *testing.T) {
+	ss := &countingServerService{}
+	svr := easyrpc.NewServer()
+	svr.RegisterService(ss)
+
+	go func() {
+		err := svr.Start(":8092")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	policy := easyrpc.DefaultRetryPolicy()
+	policy.MaxAttempts = 5
+	policy.InitialBackoff = time.Millisecond
+
+	cfg := breaker.DefaultConfig()
+	cfg.ConsecutiveFailures = 1
+	cfg.OpenTimeout = time.Minute
+
+	client, err := easyrpc.NewClientBuilder(":8092").
+		RetryPolicy(policy).
+		Idempotent("retry-service").
+		CircuitBreaker(cfg).
+		Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	cs := &retryClientService{}
+	client.InitService(cs)
+
+	// 首次失败后熔断，唯一节点的熔断器处于 open 状态时不再重试
+	_, err = cs.Flaky(context.Background(), &testReq{Name: "jrmarcco"})
+	require.ErrorIs(t, err, breaker.ErrOpen)
+	require.Equal(t, int32(1), ss.calls.Load())
+}
+
+func TestRetryDoesNotMutateRequest(t *testing.T) {
+	svr := easyrpc.NewServer()
+	svr.RegisterService(&retryServerService{failures: 2})
+
+	go func() {
+		err := svr.Start(":8096")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	policy := easyrpc.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+
+	client, err := easyrpc.NewClientBuilder(":8096").
+		RetryPolicy(policy).
+		Idempotent("retry-service").
+		Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	// 调用方传入的请求在重试时保持不变
+	req := &message.Req{
+		Serializer: (&json.Serializer{}).Code(),
+		Service:    "retry-service",
+		Method:     "Flaky",
+		Body:       []byte(`{"Name":"jrmarcco"}`),
+		Meta:       map[string]string{"trace-id": "1"},
+	}
+	req.SetLength()
+	headLen := req.HeadLen
+
+	resp, err := client.Call(context.Background(), req)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Msg":"hello jrmarcco at attempt 3"}`, string(resp.Body))
+	require.Equal(t, map[string]string{"trace-id": "1"}, req.Meta)
+	require.Equal(t, headLen, req.HeadLen)
+}
@@ -0,0 +1,153 @@
+//go:build e2e
+
+package integration
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	easyrpc "github.com/JrMarcco/easy-rpc"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type taggedClientService struct {
+	SayHello func(ctx context.Context, req *testReq) (*testResp, error) `rpc:"name=say_hello,compressor=gzip"`
+	Flaky    func(ctx context.Context, req *testReq) (*testResp, error) `rpc:"name=flaky,retries=2"`
+	Slow     func(ctx context.Context, req *testReq) (*testResp, error) `rpc:"name=slow,timeout=50ms"`
+	Notify   func(ctx context.Context, req *testReq) (*testResp, error) `rpc:"oneway"`
+}
+
+func (cs *taggedClientService) Name() string {
+	return "tagged-service"
+}
+
+type taggedServerService struct {
+	_ struct{} `rpc:"method=SayHello,name=say_hello"`
+	_ struct{} `rpc:"method=Flaky,name=flaky"`
+	_ struct{} `rpc:"method=Slow,name=slow"`
+
+	attempts atomic.Int32
+	notified chan string
+}
+
+func (ss *taggedServerService) Name() string {
+	return "tagged-service"
+}
+
+func (ss *taggedServerService) SayHello(_ context.Context, req *testReq) (*testResp, error) {
+	return &testResp{Msg: "hello " + req.Name}, nil
+}
+
+func (ss *taggedServerService) Flaky(_ context.Context, req *testReq) (*testResp, error) {
+	if ss.attempts.Add(1) < 3 {
+		return nil, easyrpc.Errorf(easyrpc.CodeUnavailable, "try again")
+	}
+	return &testResp{Msg: "hello " + req.Name}, nil
+}
+
+func (ss *taggedServerService) Slow(ctx context.Context, req *testReq) (*testResp, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(200 * time.Millisecond):
+		return &testResp{Msg: "hello " + req.Name}, nil
+	}
+}
+
+func (ss *taggedServerService) Notify(_ context.Context, req *testReq) (*testResp, error) {
+	ss.notified <- req.Name
+	return &testResp{}, nil
+}
+
+func TestTag(t *testing.T) {
+	ss := &taggedServerService{notified: make(chan string, 1)}
+
+	svr := easyrpc.NewServer(easyrpc.WithReflection())
+	svr.RegisterService(ss)
+
+	go func() {
+		err := svr.Start(":8090")
+		require.ErrorIs(t, err, easyrpc.ErrServerClosed)
+	}()
+	defer func() { _ = svr.Shutdown(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	client, err := easyrpc.NewClientBuilder(":8090").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+
+	cs := &taggedClientService{}
+	client.InitService(cs)
+
+	ctx := context.Background()
+	req := &testReq{Name: "jrmarcco"}
+
+	resp, err := cs.SayHello(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "hello jrmarcco", resp.Msg)
+
+	// 原来的方法名仍然可以调用
+	resp = &testResp{}
+	require.NoError(t, client.Invoke(ctx, "tagged-service", "SayHello", req, resp))
+	assert.Equal(t, "hello jrmarcco", resp.Msg)
+
+	// 没有声明幂等，通过标签指定的重试次数重试
+	resp, err = cs.Flaky(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "hello jrmarcco", resp.Msg)
+	assert.Equal(t, int32(3), ss.attempts.Load())
+
+	_, err = cs.Slow(ctx, req)
+	assert.Equal(t, easyrpc.CodeDeadlineExceeded, easyrpc.CodeOf(err))
+
+	_, err = cs.Notify(ctx, req)
+	require.NoError(t, err)
+	select {
+	case name := <-ss.notified:
+		assert.Equal(t, "jrmarcco", name)
+	case <-time.After(time.Second):
+		require.FailNow(t, "oneway call not received")
+	}
+
+	services, err := client.Reflect(ctx, "tagged-service")
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	names := make([]string, 0, len(services[0].Methods))
+	for _, method := range services[0].Methods {
+		names = append(names, method.Name)
+	}
+	assert.Equal(t, []string{"Notify", "flaky", "say_hello", "slow"}, names)
+}
+
+type invalidTagClientService struct {
+	SayHello func(ctx context.Context, req *testReq) (*testResp, error) `rpc:"timeout=fast"`
+}
+
+func (cs *invalidTagClientService) Name() string {
+	return "tagged-service"
+}
+
+type invalidTagServerService struct {
+	_ struct{} `rpc:"method=Unknown,name=unknown"`
+}
+
+func (ss *invalidTagServerService) Name() string {
+	return "tagged-service"
+}
+
+func TestInvalidTag(t *testing.T) {
+	r := memory.NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	client, err := easyrpc.NewClientBuilder().Registry(r, "tagged-service").Build()
+	require.NoError(t, err)
+	defer func() { _ = client.Close() }()
+	assert.Panics(t, func() { client.InitService(&invalidTagClientService{}) })
+
+	svr := easyrpc.NewServer()
+	assert.Panics(t, func() { svr.RegisterService(&invalidTagServerService{}) })
+}
@@ -0,0 +1,191 @@
+package easyrpc
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/compress"
+	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/serialize"
+	jsonserialize "github.com/JrMarcco/easy-rpc/serialize/json"
+)
+
+// CallOption 单次调用的选项，覆盖客户端的默认配置。
+type CallOption func(o *callOptions)
+
+type callOptions struct {
+	serializer serialize.Serializer
+	compressor compress.Compressor
+	meta       map[string]string
+
+	timeout time.Duration
+	oneway  bool
+	retry   *RetryPolicy
+}
+
+// WithCallSerializer 指定本次调用的序列化协议，服务端需要支持该协议。
+func WithCallSerializer(serializer serialize.Serializer) CallOption {
+	return func(o *callOptions) {
+		o.serializer = serializer
+	}
+}
+
+// WithCallCompressor 指定本次调用请求体的压缩算法，服务端需要支持该算法。
+func WithCallCompressor(compressor compress.Compressor) CallOption {
+	return func(o *callOptions) {
+		o.compressor = compressor
+	}
+}
+
+// WithCallMeta 为本次调用附加 meta，与框架内部使用的 key 冲突时以框架为准。
+func WithCallMeta(key, val string) CallOption {
+	return func(o *callOptions) {
+		if o.meta == nil {
+			o.meta = make(map[string]string, 1)
+		}
+		o.meta[key] = val
+	}
+}
+
+// WithCallTimeout 设置本次调用的超时时间，ctx 的超时时间更早时以 ctx 为准。
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithCallOneway 将本次调用作为 oneway 调用，效果与 ContextWithOneway 相同。
+func WithCallOneway() CallOption {
+	return func(o *callOptions) {
+		o.oneway = true
+	}
+}
+
+// WithCallRetryPolicy 指定本次调用的重试策略，代替客户端配置的重试和对冲策略。
+//
+// 显式指定重试策略意味着调用方确认方法是幂等的，因此不再要求通过 ClientBuilder.Idempotent 声明；
+// MaxAttempts 小于等于 1 时不重试。
+func WithCallRetryPolicy(policy RetryPolicy) CallOption {
+	return func(o *callOptions) {
+		o.retry = &policy
+	}
+}
+
+// Invoke 调用服务的方法，不需要事先通过 InitService 声明客户端的服务结构体。
+//
+// req 和 resp 需要能被使用的序列化协议处理，例如使用 proto 序列化时必须是 proto.Message，
+// resp 必须是指针。服务端返回错误时返回 *Error。
+func (c *Client) Invoke(ctx context.Context, service, method string, req, resp any, opts ...CallOption) error {
+	o := callOptions{
+		serializer: c.serializer,
+		compressor: c.compressor,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if o.oneway {
+		ctx = ContextWithOneway(ctx)
+	}
+
+	reqBody, err := o.serializer.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	// 压缩 request body
+	compressedBody, err := o.compressor.Compress(reqBody)
+	if err != nil {
+		return err
+	}
+
+	meta := c.metaFromContext(ctx)
+	for k, v := range o.meta {
+		if _, ok := meta[k]; !ok {
+			meta[k] = v
+		}
+	}
+
+	msg := &message.Req{
+		Version:    message.VersionStatus,
+		Compressor: o.compressor.Code(),
+		Serializer: o.serializer.Code(),
+		Service:    service,
+		Method:     method,
+		Body:       compressedBody,
+		Meta:       meta,
+	}
+	msg.SetLength()
+
+	res, err := c.callWithPolicy(ctx, msg, o.retry)
+	if err != nil {
+		return err
+	}
+
+	// 处理 Resp.Err（服务端回传的错误）
+	if len(res.Err) != 0 || res.Status != uint8(CodeOK) {
+		return remoteError(res)
+	}
+	if res.BodyLen == 0 {
+		return nil
+	}
+	return o.serializer.Unmarshal(res.Body, resp)
+}
+
+// InvokeJSON 使用 json 格式的请求调用服务的方法，返回 json 格式的响应，
+// 适用于网关以及调试工具等没有服务的 Go 类型的场景。
+//
+// 无论客户端配置了什么序列化协议，本次调用固定使用 json 序列化。
+func (c *Client) InvokeJSON(ctx context.Context, service, method string, req json.RawMessage, opts ...CallOption) (json.RawMessage, error) {
+	if len(req) == 0 {
+		req = json.RawMessage("{}")
+	}
+	if !json.Valid(req) {
+		return nil, Errorf(CodeInvalidArgument, "[easy-rpc] request of %s.%s is not valid json", service, method)
+	}
+
+	var resp json.RawMessage
+	// 不能写入调用方的切片，调用方可能在多个协程中复用同一个切片
+	opts = append(slices.Clip(opts), WithCallSerializer(&jsonserialize.Serializer{}))
+	if err := c.Invoke(ctx, service, method, req, &resp, opts...); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// InvokeMap 与 InvokeJSON 相同，只是请求和响应使用 map[string]any 表示。
+func (c *Client) InvokeMap(ctx context.Context, service, method string, req map[string]any, opts ...CallOption) (map[string]any, error) {
+	if req == nil {
+		req = map[string]any{}
+	}
+
+	var resp map[string]any
+	// 不能写入调用方的切片，调用方可能在多个协程中复用同一个切片
+	opts = append(slices.Clip(opts), WithCallSerializer(&jsonserialize.Serializer{}))
+	if err := c.Invoke(ctx, service, method, req, &resp, opts...); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Unary 创建调用服务方法的强类型函数，与 InitService 生成的函数效果相同，
+// 但不需要声明服务结构体，也不需要在每次调用时通过反射创建响应。
+//
+// opts 作用于返回函数的每一次调用，例如：
+//
+//	sayHello := easyrpc.Unary[HelloReq, HelloResp](client, "user-service", "SayHello")
+//	resp, err := sayHello(ctx, &HelloReq{Name: "jrmarcco"})
+func Unary[Req, Resp any](client *Client, service, method string, opts ...CallOption) func(ctx context.Context, req *Req) (*Resp, error) {
+	return func(ctx context.Context, req *Req) (*Resp, error) {
+		resp := new(Resp)
+		err := client.Invoke(ctx, service, method, req, resp, opts...)
+		return resp, err
+	}
+}
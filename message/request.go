@@ -10,6 +10,14 @@ const (
 	equalSign = '\t'
 )
 
+// 协议版本，通过请求的 Version 字段协商响应的格式。
+const (
+	// VersionLegacy 初始版本，响应中没有状态码
+	VersionLegacy uint8 = iota
+	// VersionStatus 响应中携带状态码
+	VersionStatus
+)
+
 // Req rpc 请求信息
 //
 // | 	  head length 4  	| 					   	 body length 4           		      |
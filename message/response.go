@@ -4,25 +4,53 @@ import (
 	"encoding/binary"
 )
 
+// statusFlag head length 的最高位，置位时 message id 之后有 1 个字节的状态码。
+const statusFlag uint32 = 1 << 31
+
+// HeadLenMask 从 head length 字段中去掉标志位，得到 head 的实际长度。
+const HeadLenMask = statusFlag - 1
+
 // Resp rpc 响应信息
 //
 // | 	  head length 4  	| 	body length 4 	|
-// |      message id  4     |
+// |      message id  4     |   status 1        |
 // |  	  error message	    |
 // | 	  response body	    |
+//
+// 只有 VersionStatus 及以上版本的响应才有 status，此时 head length 的最高位置位；
+// 服务端按请求的 Version 选择响应格式，旧版本的客户端收到的仍然是没有 status 的响应，
+// 新版本的客户端收到旧版本服务端的响应时，按最高位没有置位识别为旧格式。
 type Resp struct {
 	HeadLen uint32
 	BodyLen uint32
 
 	MessageId uint32
 
+	// Version 响应格式的版本，低于 VersionStatus 时不编码 Status
+	Version uint8
+	// Status 调用状态码，0 表示成功
+	Status uint8
+
 	Err  []byte
 	Body []byte
 }
 
+// hasStatus 响应中是否携带状态码
+func (resp *Resp) hasStatus() bool {
+	return resp.Version >= VersionStatus
+}
+
+// errOffset 错误信息在响应中的起始位置
+func (resp *Resp) errOffset() uint32 {
+	if resp.hasStatus() {
+		return 13
+	}
+	return 12
+}
+
 func (resp *Resp) SetLength() {
 	// 设置 head 长度
-	resp.HeadLen = 12 + uint32(len(resp.Err))
+	resp.HeadLen = resp.errOffset() + uint32(len(resp.Err))
 	// 设置 body 长度
 	resp.BodyLen = uint32(len(resp.Body))
 }
@@ -31,14 +59,22 @@ func EncodeResp(resp *Resp) []byte {
 	bs := make([]byte, resp.HeadLen+resp.BodyLen)
 
 	// 写入 head 长度
-	binary.BigEndian.PutUint32(bs[:4], resp.HeadLen)
+	headLen := resp.HeadLen
+	if resp.hasStatus() {
+		headLen |= statusFlag
+	}
+	binary.BigEndian.PutUint32(bs[:4], headLen)
 	// 写入 body 长度
 	binary.BigEndian.PutUint32(bs[4:8], resp.BodyLen)
 	// 写入 message id
 	binary.BigEndian.PutUint32(bs[8:12], resp.MessageId)
+	// 写入 status
+	if resp.hasStatus() {
+		bs[12] = resp.Status
+	}
 
 	// 写入 err
-	copy(bs[12:resp.HeadLen], resp.Err)
+	copy(bs[resp.errOffset():resp.HeadLen], resp.Err)
 	// 写入 body
 	copy(bs[resp.HeadLen:], resp.Body)
 
@@ -49,15 +85,21 @@ func DecodeResp(data []byte) *Resp {
 	resp := &Resp{}
 
 	// 解码 head 长度
-	resp.HeadLen = binary.BigEndian.Uint32(data[:4])
+	headLen := binary.BigEndian.Uint32(data[:4])
+	resp.HeadLen = headLen & HeadLenMask
 	// 解码 body 长度
 	resp.BodyLen = binary.BigEndian.Uint32(data[4:8])
 	// 解码 message id
 	resp.MessageId = binary.BigEndian.Uint32(data[8:12])
+	// 解码 status
+	if headLen&statusFlag != 0 {
+		resp.Version = VersionStatus
+		resp.Status = data[12]
+	}
 
 	// 解码 err
-	if resp.HeadLen > 12 {
-		resp.Err = data[12:resp.HeadLen]
+	if offset := resp.errOffset(); resp.HeadLen > offset {
+		resp.Err = data[offset:resp.HeadLen]
 	}
 	// 解码 body
 	if resp.BodyLen > 0 {
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,18 @@ func TestResp(t *testing.T) {
 	}{
 		{
 			name: "basic",
+			resp: &Resp{
+				HeadLen:   12,
+				BodyLen:   12,
+				MessageId: 1,
+				Version:   VersionStatus,
+				Status:    2,
+
+				Err:  []byte("test-err"),
+				Body: []byte("test-data"),
+			},
+		}, {
+			name: "legacy",
 			resp: &Resp{
 				HeadLen:   12,
 				BodyLen:   12,
@@ -51,3 +64,26 @@ func TestResp(t *testing.T) {
 		})
 	}
 }
+
+func TestRespCompatibility(t *testing.T) {
+	resp := &Resp{
+		MessageId: 1,
+		Version:   VersionStatus,
+		Status:    2,
+		Err:       []byte("test-err"),
+		Body:      []byte("test-data"),
+	}
+	resp.SetLength()
+	data := EncodeResp(resp)
+
+	// 携带状态码的响应通过 head length 的最高位标识，去掉标志位后的长度不变
+	assert.Equal(t, resp.HeadLen, binary.BigEndian.Uint32(data[:4])&HeadLenMask)
+	assert.NotEqual(t, resp.HeadLen, binary.BigEndian.Uint32(data[:4]))
+
+	// 旧版本的响应没有状态码，错误信息紧跟在 message id 之后
+	legacy := &Resp{MessageId: 1, Err: []byte("test-err")}
+	legacy.SetLength()
+	data = EncodeResp(legacy)
+	assert.Equal(t, uint32(20), binary.BigEndian.Uint32(data[:4]))
+	assert.Equal(t, []byte("test-err"), data[12:20])
+}
@@ -0,0 +1,17 @@
+package easyrpc
+
+import (
+	"github.com/JrMarcco/easy-rpc/overload"
+)
+
+// throttle 获取目标地址对应的自适应限流，未开启时返回 nil。
+func (c *Client) throttle(addr string) *overload.Throttle {
+	if c.throttleCfg == nil {
+		return nil
+	}
+	if val, ok := c.throttles.Load(addr); ok {
+		return val.(*overload.Throttle)
+	}
+	val, _ := c.throttles.LoadOrStore(addr, overload.NewThrottle(c.throttleCfg.k, c.throttleCfg.window))
+	return val.(*overload.Throttle)
+}
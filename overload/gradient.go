@@ -0,0 +1,166 @@
+package overload
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var _ Limiter = (*Gradient)(nil)
+
+// GradientConfig 梯度限流配置。
+type GradientConfig struct {
+	// 初始并发上限
+	InitialLimit int
+	// 并发上限的取值范围
+	MinLimit int
+	MaxLimit int
+
+	// 并发上限的平滑系数，取值 (0, 1]，越大调整越快
+	Smoothing float64
+	// 延迟容忍倍数，短期延迟不超过长期延迟的 Tolerance 倍时不降低并发上限
+	Tolerance float64
+	// 采样窗口，每个窗口内的延迟取平均值作为一个样本，每个窗口调整一次并发上限
+	SampleWindow time.Duration
+	// 长期延迟的 EWMA 窗口，单位为样本数
+	LongWindow int
+}
+
+// DefaultGradientConfig 默认配置。
+func DefaultGradientConfig() GradientConfig {
+	return GradientConfig{
+		InitialLimit: 64,
+		MinLimit:     8,
+		MaxLimit:     1024,
+		Smoothing:    0.2,
+		Tolerance:    1.5,
+		SampleWindow: 100 * time.Millisecond,
+		LongWindow:   600,
+	}
+}
+
+// Gradient 基于延迟梯度的自适应并发限制器。
+//
+// 以长期延迟（EWMA）作为无排队时的基准，与每个请求的实际延迟比较得到梯度：
+// 延迟升高说明请求开始排队，按梯度降低并发上限；延迟平稳时并发上限缓慢增长。
+type Gradient struct {
+	cfg GradientConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	longRtt  float64
+
+	// 当前采样窗口的统计数据
+	windowStart    time.Time
+	windowRttSum   time.Duration
+	windowCount    int
+	windowInflight int
+
+	now func() time.Time
+}
+
+func (g *Gradient) Acquire() (func(), bool) {
+	g.mu.Lock()
+	if float64(g.inflight) >= g.limit {
+		g.mu.Unlock()
+		return nil, false
+	}
+	g.inflight++
+	inflight := g.inflight
+	g.mu.Unlock()
+
+	start := g.now()
+	return func() {
+		g.onSample(g.now().Sub(start), inflight)
+	}, true
+}
+
+// Limit 当前的并发上限。
+func (g *Gradient) Limit() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return int(g.limit)
+}
+
+func (g *Gradient) onSample(rtt time.Duration, inflight int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.inflight--
+
+	now := g.now()
+	if g.windowCount == 0 {
+		g.windowStart = now
+	}
+	g.windowRttSum += rtt
+	g.windowCount++
+	g.windowInflight = max(g.windowInflight, inflight)
+
+	if now.Sub(g.windowStart) < g.cfg.SampleWindow {
+		return
+	}
+
+	shortRtt := float64(g.windowRttSum) / float64(g.windowCount)
+	maxInflight := g.windowInflight
+	g.windowRttSum, g.windowCount, g.windowInflight = 0, 0, 0
+
+	if shortRtt <= 0 {
+		return
+	}
+	g.update(shortRtt, maxInflight)
+}
+
+func (g *Gradient) update(shortRtt float64, inflight int) {
+	if g.longRtt == 0 {
+		g.longRtt = shortRtt
+	} else {
+		alpha := 2 / (float64(g.cfg.LongWindow) + 1)
+		g.longRtt = g.longRtt*(1-alpha) + shortRtt*alpha
+	}
+
+	// 负载恢复后长期延迟会明显高于短期延迟，让长期延迟更快地回落
+	if g.longRtt/shortRtt > 2 {
+		g.longRtt *= 0.95
+	}
+
+	// 并发远未达到上限时，延迟样本不能反映上限是否合适
+	if float64(inflight) < g.limit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, g.cfg.Tolerance*g.longRtt/shortRtt))
+	// 额外留出 sqrt(limit) 的排队空间，使并发上限在延迟平稳时能够增长
+	newLimit := g.limit*gradient + math.Sqrt(g.limit)
+	newLimit = g.limit*(1-g.cfg.Smoothing) + newLimit*g.cfg.Smoothing
+
+	g.limit = math.Max(float64(g.cfg.MinLimit), math.Min(float64(g.cfg.MaxLimit), newLimit))
+}
+
+func NewGradient(cfg GradientConfig) *Gradient {
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = 1
+	}
+	if cfg.MaxLimit < cfg.MinLimit {
+		cfg.MaxLimit = cfg.MinLimit
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = 0.2
+	}
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = 1
+	}
+	if cfg.SampleWindow <= 0 {
+		cfg.SampleWindow = 100 * time.Millisecond
+	}
+	if cfg.LongWindow <= 0 {
+		cfg.LongWindow = 600
+	}
+
+	limit := math.Max(float64(cfg.MinLimit), math.Min(float64(cfg.MaxLimit), float64(cfg.InitialLimit)))
+	return &Gradient{
+		cfg:   cfg,
+		limit: limit,
+		now:   time.Now,
+	}
+}
@@ -0,0 +1,98 @@
+package overload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestGradientReject(t *testing.T) {
+	g := NewGradient(GradientConfig{InitialLimit: 2, MinLimit: 2, MaxLimit: 2})
+
+	_, ok := g.Acquire()
+	require.True(t, ok)
+	done, ok := g.Acquire()
+	require.True(t, ok)
+
+	_, ok = g.Acquire()
+	assert.False(t, ok)
+
+	done()
+	_, ok = g.Acquire()
+	assert.True(t, ok)
+}
+
+// runBatch 以满并发执行一批请求，每个请求的延迟都是 rtt。
+func runBatch(t *testing.T, g *Gradient, clock *fakeClock, rtt time.Duration) {
+	limit := g.Limit()
+	dones := make([]func(), 0, limit)
+	for i := 0; i < limit; i++ {
+		done, ok := g.Acquire()
+		require.True(t, ok)
+		dones = append(dones, done)
+	}
+	clock.Advance(rtt)
+	for _, done := range dones {
+		done()
+	}
+}
+
+func TestGradient(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+
+	cfg := DefaultGradientConfig()
+	cfg.InitialLimit = 32
+	cfg.SampleWindow = 10 * time.Millisecond
+	g := NewGradient(cfg)
+	g.now = clock.Now
+
+	// 延迟平稳时并发上限增长
+	for i := 0; i < 10; i++ {
+		runBatch(t, g, clock, 10*time.Millisecond)
+	}
+	grown := g.Limit()
+	assert.Greater(t, grown, 32)
+
+	// 延迟升高说明开始排队，并发上限降低
+	for i := 0; i < 5; i++ {
+		runBatch(t, g, clock, 100*time.Millisecond)
+	}
+	assert.Less(t, g.Limit(), grown)
+}
+
+func TestThrottle(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	th := NewThrottle(2, 10*time.Second)
+	th.now = clock.Now
+	th.random = func() float64 { return 0.5 }
+
+	// 服务端正常时不拒绝
+	for i := 0; i < 100; i++ {
+		require.True(t, th.Allow())
+		th.Record(true)
+	}
+
+	// 服务端持续过载，拒绝概率逐渐超过 0.5
+	for i := 0; i < 400; i++ {
+		th.Record(false)
+	}
+	assert.False(t, th.Allow())
+
+	// 过载的统计滑出窗口后恢复
+	clock.Advance(11 * time.Second)
+	assert.True(t, th.Allow())
+}
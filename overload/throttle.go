@@ -0,0 +1,84 @@
+package overload
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const throttleBuckets = 10
+
+type throttleBucket struct {
+	start    int64
+	requests int
+	accepts  int
+}
+
+// Throttle 客户端自适应限流。
+//
+// 统计滑动窗口内的请求数 requests 和被服务端接受的请求数 accepts，
+// 以 max(0, (requests - K * accepts) / (requests + 1)) 的概率直接在客户端拒绝请求。
+// 服务端正常时 requests 约等于 accepts，不会拒绝；服务端持续过载时拒绝的比例逐渐升高，
+// 从而减少发往过载服务端的流量。
+type Throttle struct {
+	k float64
+
+	mu         sync.Mutex
+	buckets    []throttleBucket
+	bucketSize int64
+
+	now    func() time.Time
+	random func() float64
+}
+
+// Allow 判断请求是否允许发出，收到服务端响应的请求需要通过 Record 上报结果。
+func (t *Throttle) Allow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests, accepts := t.sum(t.now())
+	p := (float64(requests) - t.k*float64(accepts)) / float64(requests+1)
+	return p <= 0 || t.random() >= p
+}
+
+// Record 上报请求结果，accepted 表示请求没有因为过载被服务端拒绝。
+func (t *Throttle) Record(accepted bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := t.now().UnixNano() / t.bucketSize
+	bkt := &t.buckets[start%int64(len(t.buckets))]
+	if bkt.start != start {
+		*bkt = throttleBucket{start: start}
+	}
+	bkt.requests++
+	if accepted {
+		bkt.accepts++
+	}
+}
+
+func (t *Throttle) sum(now time.Time) (requests, accepts int) {
+	oldest := now.UnixNano()/t.bucketSize - int64(len(t.buckets)) + 1
+	for _, bkt := range t.buckets {
+		if bkt.start >= oldest {
+			requests += bkt.requests
+			accepts += bkt.accepts
+		}
+	}
+	return requests, accepts
+}
+
+// NewThrottle 创建客户端自适应限流，k 一般取 2，越小越激进。
+func NewThrottle(k float64, window time.Duration) *Throttle {
+	bucketSize := int64(window) / throttleBuckets
+	if bucketSize <= 0 {
+		bucketSize = int64(time.Second)
+	}
+	return &Throttle{
+		k:          k,
+		buckets:    make([]throttleBucket, throttleBuckets),
+		bucketSize: bucketSize,
+		now:        time.Now,
+		random:     rand.Float64,
+	}
+}
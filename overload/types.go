@@ -0,0 +1,8 @@
+package overload
+
+// Limiter 并发限制器，用于在过载时尽早拒绝请求。
+type Limiter interface {
+	// Acquire 尝试获取执行许可，返回 false 时应当拒绝请求。
+	// 获取成功时，必须在请求处理结束后调用 done。
+	Acquire() (done func(), ok bool)
+}
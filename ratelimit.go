@@ -0,0 +1,77 @@
+package easyrpc
+
+import (
+	"strconv"
+
+	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/ratelimit"
+)
+
+// RateLimitRule 服务端限流规则。
+//
+// 请求匹配到的所有规则都会被检查，任意一条规则拒绝时请求即被拒绝，
+// 被拒绝的请求返回 CodeResourceExhausted，且不会解压和反序列化请求体。
+// 前面的规则已经计入的请求在被拒绝后，通过 ratelimit.Reverter 归还额度，没有实现 Reverter 的限流器仍然计入。
+// 健康检查和反射等内置服务不受限流规则的影响。
+//
+// 每条规则按在 WithRateLimit 中的位置分别计数，多条规则可以共用同一个限流器。
+type RateLimitRule struct {
+	// 限流的服务，为空时匹配所有服务
+	Service string
+	// 限流的方法，为空时匹配服务的所有方法
+	Method string
+	// 从 meta 中获取调用方标识的 key，例如 MetaKeyCaller。
+	// 设置后按调用方分别限流，为空时所有调用方共享同一个限额。
+	CallerKey string
+
+	Limiter ratelimit.Limiter
+}
+
+func (r RateLimitRule) match(req *message.Req) bool {
+	if r.Service != "" && r.Service != req.Service {
+		return false
+	}
+	return r.Method == "" || r.Method == req.Method
+}
+
+// key 限流计数的 key，由规则的位置、作用范围和调用方标识组成。
+func (r RateLimitRule) key(index int, req *message.Req) string {
+	key := strconv.Itoa(index) + "#" + r.Service
+	if r.Method != "" {
+		key = methodKey(key, r.Method)
+	}
+	if r.CallerKey != "" {
+		key += "@" + req.Meta[r.CallerKey]
+	}
+	return key
+}
+
+// allow 按限流规则判断请求是否允许通过。
+func (s *Server) allow(req *message.Req) error {
+	if isInternalService(req.Service) {
+		return nil
+	}
+
+	for i, rule := range s.rateLimitRules {
+		if !rule.match(req) {
+			continue
+		}
+		if !rule.Limiter.Allow(rule.key(i, req)) {
+			s.revert(req, i)
+			return Errorf(CodeResourceExhausted, "[easy-rpc] rate limit exceeded for %s.%s", req.Service, req.Method)
+		}
+	}
+	return nil
+}
+
+// revert 归还被拒绝的请求在前 n 条规则中消耗的额度。
+func (s *Server) revert(req *message.Req, n int) {
+	for i, rule := range s.rateLimitRules[:n] {
+		if !rule.match(req) {
+			continue
+		}
+		if reverter, ok := rule.Limiter.(ratelimit.Reverter); ok {
+			reverter.Revert(rule.key(i, req))
+		}
+	}
+}
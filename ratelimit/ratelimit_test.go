@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func allowN(l Limiter, key string, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if l.Allow(key) {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tb := NewTokenBucket(10, 5)
+	tb.now = clock.Now
+
+	// 初始为满桶，允许突发 5 个请求
+	assert.Equal(t, 5, allowN(tb, "a", 10))
+	// 不同 key 互不影响
+	assert.Equal(t, 5, allowN(tb, "b", 10))
+
+	// 100ms 补充 1 个令牌
+	clock.Advance(100 * time.Millisecond)
+	assert.Equal(t, 1, allowN(tb, "a", 10))
+
+	// 桶容量上限为 5
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, 5, allowN(tb, "a", 10))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	sw := NewSlidingWindow(time.Second, 10)
+	sw.now = clock.Now
+
+	assert.Equal(t, 10, allowN(sw, "a", 20))
+	assert.Equal(t, 10, allowN(sw, "b", 20))
+
+	// 进入下一个窗口 500ms 后，上一个窗口仍有一半的请求计入滑动窗口
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, 5, allowN(sw, "a", 20))
+
+	// 过去两个窗口后计数清零
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, 10, allowN(sw, "a", 20))
+}
+
+func TestTokenBucketEvict(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tb := NewTokenBucket(1, 5)
+	tb.now = clock.Now
+
+	for i := 0; i < 100; i++ {
+		tb.Allow(fmt.Sprintf("caller-%d", i))
+	}
+	assert.Len(t, tb.buckets, 100)
+
+	clock.Advance(4 * time.Second)
+	assert.Equal(t, 5, allowN(tb, "a", 10))
+	assert.Len(t, tb.buckets, 101)
+
+	// 补满的令牌桶被移除，未补满的令牌桶保留
+	clock.Advance(time.Second)
+	assert.Equal(t, 5, allowN(tb, "b", 10))
+	assert.Len(t, tb.buckets, 2)
+	assert.Equal(t, 1, allowN(tb, "a", 10))
+}
+
+func TestSlidingWindowEvict(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	sw := NewSlidingWindow(time.Second, 10)
+	sw.now = clock.Now
+
+	for i := 0; i < 100; i++ {
+		sw.Allow(fmt.Sprintf("caller-%d", i))
+	}
+	assert.Equal(t, 10, allowN(sw, "a", 20))
+	assert.Len(t, sw.windows, 101)
+
+	// 仍然计入滑动窗口的计数保留
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, 5, allowN(sw, "a", 20))
+	assert.Len(t, sw.windows, 101)
+
+	// 过去不止一个窗口的计数被移除
+	clock.Advance(time.Second)
+	assert.Equal(t, 10, allowN(sw, "b", 20))
+	assert.Len(t, sw.windows, 2)
+}
+
+func TestRevert(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tb := NewTokenBucket(10, 5)
+	tb.now = clock.Now
+	sw := NewSlidingWindow(time.Second, 5)
+	sw.now = clock.Now
+
+	tcs := []struct {
+		name    string
+		limiter interface {
+			Limiter
+			Reverter
+		}
+	}{
+		{name: "token bucket", limiter: tb},
+		{name: "sliding window", limiter: sw},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, 5, allowN(tc.limiter, "a", 10))
+
+			// 归还的额度可以再次使用
+			tc.limiter.Revert("a")
+			tc.limiter.Revert("a")
+			assert.Equal(t, 2, allowN(tc.limiter, "a", 10))
+
+			// 不存在的 key 不受影响
+			tc.limiter.Revert("b")
+			assert.Equal(t, 5, allowN(tc.limiter, "b", 10))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	_ Limiter  = (*SlidingWindow)(nil)
+	_ Reverter = (*SlidingWindow)(nil)
+)
+
+type windowState struct {
+	start int64 // 当前窗口的起始时间
+	curr  int   // 当前窗口的请求数
+	prev  int   // 上一个窗口的请求数
+}
+
+// SlidingWindow 滑动窗口限流。
+//
+// 只记录当前窗口和上一个窗口的请求数，
+// 按上一个窗口与滑动窗口的重叠比例估算滑动窗口内的请求数，避免固定窗口在边界处的突发流量。
+type SlidingWindow struct {
+	mu      sync.Mutex
+	window  int64
+	limit   int
+	windows map[string]*windowState
+
+	// 上次清理时的窗口起始时间
+	lastSweep int64
+
+	now func() time.Time
+}
+
+func (sw *SlidingWindow) Allow(key string) bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := sw.now().UnixNano()
+	start := now - now%sw.window
+	sw.sweep(start)
+
+	state, ok := sw.windows[key]
+	if !ok {
+		state = &windowState{start: start}
+		sw.windows[key] = state
+	}
+
+	switch {
+	case start == state.start+sw.window:
+		// 进入下一个窗口
+		state.prev, state.curr = state.curr, 0
+		state.start = start
+	case start > state.start:
+		// 已经过去不止一个窗口
+		state.prev, state.curr = 0, 0
+		state.start = start
+	}
+
+	// 上一个窗口与滑动窗口重叠的比例
+	overlap := float64(sw.window-(now-start)) / float64(sw.window)
+	if float64(state.prev)*overlap+float64(state.curr) >= float64(sw.limit) {
+		return false
+	}
+	state.curr++
+	return true
+}
+
+// Revert 从当前窗口的计数中减去一个请求。
+func (sw *SlidingWindow) Revert(key string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if state, ok := sw.windows[key]; ok && state.curr > 0 {
+		state.curr--
+	}
+}
+
+// sweep 每个窗口移除一次已经过去不止一个窗口的计数。
+// 这些计数不再计入滑动窗口，与新建的计数等价，移除后不影响限流结果，避免 key 数量无限增长。
+func (sw *SlidingWindow) sweep(start int64) {
+	if start == sw.lastSweep {
+		return
+	}
+	sw.lastSweep = start
+
+	for key, state := range sw.windows {
+		if state.start < start-sw.window {
+			delete(sw.windows, key)
+		}
+	}
+}
+
+// NewSlidingWindow 创建滑动窗口限流器，window 内最多允许 limit 个请求。
+func NewSlidingWindow(window time.Duration, limit int) *SlidingWindow {
+	return &SlidingWindow{
+		window:  int64(window),
+		limit:   limit,
+		windows: make(map[string]*windowState),
+		now:     time.Now,
+	}
+}
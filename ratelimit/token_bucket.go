@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	_ Limiter  = (*TokenBucket)(nil)
+	_ Reverter = (*TokenBucket)(nil)
+)
+
+type bucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket 令牌桶限流。
+// 令牌以固定速率放入桶中，桶满后不再增加，每个请求消耗一个令牌，允许一定程度的突发流量。
+type TokenBucket struct {
+	mu      sync.Mutex
+	rate    float64 // 每秒放入的令牌数
+	burst   float64 // 桶容量
+	buckets map[string]*bucketState
+
+	// 两次清理之间的间隔和上次清理的时间
+	sweepInterval time.Duration
+	lastSweep     time.Time
+
+	now func() time.Time
+}
+
+func (tb *TokenBucket) Allow(key string) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.now()
+	tb.sweep(now)
+
+	state, ok := tb.buckets[key]
+	if !ok {
+		// 新的 key 从满桶开始
+		state = &bucketState{tokens: tb.burst, last: now}
+		tb.buckets[key] = state
+	}
+
+	// 按流逝的时间补充令牌
+	elapsed := now.Sub(state.last).Seconds()
+	state.tokens = math.Min(tb.burst, state.tokens+elapsed*tb.rate)
+	state.last = now
+
+	if state.tokens < 1 {
+		return false
+	}
+	state.tokens--
+	return true
+}
+
+// Revert 归还一个令牌，令牌数不超过桶容量。
+func (tb *TokenBucket) Revert(key string) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if state, ok := tb.buckets[key]; ok {
+		state.tokens = math.Min(tb.burst, state.tokens+1)
+	}
+}
+
+// sweep 定期移除已经补满的令牌桶。
+// 补满的令牌桶与新建的令牌桶等价，移除后不影响限流结果，避免 key 数量无限增长。
+func (tb *TokenBucket) sweep(now time.Time) {
+	if now.Sub(tb.lastSweep) < tb.sweepInterval {
+		return
+	}
+	tb.lastSweep = now
+
+	for key, state := range tb.buckets {
+		if state.tokens+now.Sub(state.last).Seconds()*tb.rate >= tb.burst {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+// NewTokenBucket 创建令牌桶限流器，rate 为每秒放入的令牌数，burst 为桶容量。
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	// 至少间隔补满一个空桶所需的时间清理一次
+	sweepInterval := time.Minute
+	if rate > 0 {
+		sweepInterval = max(time.Duration(float64(burst)/rate*float64(time.Second)), time.Second)
+	}
+	return &TokenBucket{
+		rate:          rate,
+		burst:         float64(burst),
+		buckets:       make(map[string]*bucketState),
+		sweepInterval: sweepInterval,
+		now:           time.Now,
+	}
+}
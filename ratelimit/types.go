@@ -0,0 +1,14 @@
+package ratelimit
+
+// Limiter 限流器，按 key 分别计数。
+type Limiter interface {
+	// Allow 判断 key 对应的请求是否允许通过
+	Allow(key string) bool
+}
+
+// Reverter 可以撤销已经通过的请求的限流器。
+// 一个请求需要通过多个限流器时，被后面的限流器拒绝后通过 Revert 归还前面限流器中消耗的额度。
+type Reverter interface {
+	// Revert 撤销 key 最近一次通过的请求
+	Revert(key string)
+}
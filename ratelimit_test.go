@@ -0,0 +1,66 @@
+package easyrpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerAllow(t *testing.T) {
+	userReq := &message.Req{Service: "user-service", Method: "SayHello"}
+	orderReq := &message.Req{Service: "order-service", Method: "Create"}
+	healthReq := &message.Req{Service: HealthServiceName, Method: "Check"}
+
+	tcs := []struct {
+		name  string
+		rules func() []RateLimitRule
+		reqs  []*message.Req
+		want  []Code
+	}{
+		{
+			// 作用范围相同的规则共用限流器时分别计数
+			name: "shared limiter",
+			rules: func() []RateLimitRule {
+				limiter := ratelimit.NewTokenBucket(0, 2)
+				return []RateLimitRule{
+					{Limiter: limiter},
+					{Limiter: limiter},
+				}
+			},
+			reqs: []*message.Req{userReq, userReq, userReq},
+			want: []Code{CodeOK, CodeOK, CodeResourceExhausted},
+		}, {
+			// 被后面的规则拒绝时归还前面规则的额度
+			name: "revert",
+			rules: func() []RateLimitRule {
+				return []RateLimitRule{
+					{Limiter: ratelimit.NewTokenBucket(0, 2)},
+					{Service: "user-service", Limiter: ratelimit.NewTokenBucket(0, 1)},
+				}
+			},
+			reqs: []*message.Req{userReq, userReq, userReq, orderReq},
+			want: []Code{CodeOK, CodeResourceExhausted, CodeResourceExhausted, CodeOK},
+		}, {
+			name: "internal service",
+			rules: func() []RateLimitRule {
+				return []RateLimitRule{
+					{Limiter: ratelimit.NewSlidingWindow(time.Second, 1)},
+				}
+			},
+			reqs: []*message.Req{healthReq, healthReq, userReq, userReq},
+			want: []Code{CodeOK, CodeOK, CodeOK, CodeResourceExhausted},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(WithRateLimit(tc.rules()...))
+			for i, req := range tc.reqs {
+				assert.Equal(t, tc.want[i], CodeOf(s.allow(req)), "request %d", i)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package easyrpc
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ReflectionServiceName 内置反射服务的服务名
+const ReflectionServiceName = "easyrpc.reflection"
+
+type ReflectionReq struct {
+	// 服务名，为空时返回全部服务
+	Service string
+}
+
+type ReflectionResp struct {
+	Services []ServiceInfo
+}
+
+type ServiceInfo struct {
+	Name    string
+	Methods []MethodInfo
+}
+
+type MethodInfo struct {
+	Name     string
+	Request  TypeInfo
+	Response TypeInfo
+}
+
+// TypeInfo 请求或者响应的类型信息。
+type TypeInfo struct {
+	// Go 类型名，包含包路径，例如 github.com/JrMarcco/easy-rpc/internal/integration/pb.TestReq
+	GoType string
+	// proto 消息的全名，不是 proto 消息时为空
+	ProtoName string `json:",omitempty"`
+	// proto 消息所在的文件以及依赖的文件，依赖在前，每个元素都是序列化后的 descriptorpb.FileDescriptorProto
+	ProtoFiles [][]byte `json:",omitempty"`
+}
+
+// WithReflection 开启反射服务，客户端可以通过 Client.Reflect 查询服务端提供的服务和方法。
+func WithReflection() ServerOption {
+	return func(s *Server) {
+		s.reflection = true
+	}
+}
+
+var _ Service = (*reflectionService)(nil)
+
+// reflectionService 内置的反射服务，通过 WithReflection 开启。
+type reflectionService struct {
+	server *Server
+}
+
+func (r *reflectionService) Name() string {
+	return ReflectionServiceName
+}
+
+func (r *reflectionService) ListServices(_ context.Context, req *ReflectionReq) (*ReflectionResp, error) {
+	r.server.mu.RLock()
+	resp := &ReflectionResp{Services: make([]ServiceInfo, 0, len(r.server.services))}
+	for name, ps := range r.server.services {
+		if req.Service == "" || req.Service == name {
+			resp.Services = append(resp.Services, ServiceInfo{Name: name, Methods: ps.methodInfos()})
+		}
+	}
+	r.server.mu.RUnlock()
+
+	if req.Service != "" && len(resp.Services) == 0 {
+		return nil, Errorf(CodeNotFound, "[easy-rpc] service %s not found", req.Service)
+	}
+
+	slices.SortFunc(resp.Services, func(a, b ServiceInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return resp, nil
+}
+
+// methodInfos 获取服务结构体上的方法以及通过 RegisterHandler 注册的方法，按方法名排序，调用方需要持有 Server.mu。
+func (p *ProxyStub) methodInfos() []MethodInfo {
+	// 指定了网络上的方法名时只返回该方法名
+	wireNames := make(map[string]string, len(p.names))
+	for name, goName := range p.names {
+		wireNames[goName] = name
+	}
+
+	methods := make(map[string]MethodInfo, len(p.handlers))
+	for _, method := range serviceMethods(p.service) {
+		name := method.Name
+		if wireName, ok := wireNames[name]; ok {
+			name = wireName
+		}
+		methods[name] = MethodInfo{
+			Name:     name,
+			Request:  typeInfo(method.Type.In(2)),
+			Response: typeInfo(method.Type.Out(0)),
+		}
+	}
+	// handler 优先于服务结构体上的同名方法
+	for name, mh := range p.handlers {
+		info := MethodInfo{Name: name}
+		if mh.reqType != nil {
+			info.Request = typeInfo(mh.reqType)
+			info.Response = typeInfo(mh.respType)
+		}
+		methods[name] = info
+	}
+
+	res := make([]MethodInfo, 0, len(methods))
+	for _, info := range methods {
+		res = append(res, info)
+	}
+	slices.SortFunc(res, func(a, b MethodInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return res
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// serviceMethods 获取服务中可以远程调用的方法，即签名为 func(context.Context, *Req) (*Resp, error) 的导出方法。
+func serviceMethods(service Service) []reflect.Method {
+	typ := reflect.TypeOf(service)
+
+	methods := make([]reflect.Method, 0, typ.NumMethod())
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		// method.Type 的第一个参数是接收者
+		mt := method.Type
+		if mt.NumIn() != 3 || mt.NumOut() != 2 {
+			continue
+		}
+		if mt.In(1) != contextType || mt.In(2).Kind() != reflect.Pointer {
+			continue
+		}
+		if mt.Out(0).Kind() != reflect.Pointer || mt.Out(1) != errorType {
+			continue
+		}
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+func typeInfo(typ reflect.Type) TypeInfo {
+	elem := typ.Elem()
+
+	info := TypeInfo{GoType: elem.String()}
+	if elem.PkgPath() != "" {
+		info.GoType = elem.PkgPath() + "." + elem.Name()
+	}
+
+	msg, ok := reflect.New(elem).Interface().(proto.Message)
+	if !ok {
+		return info
+	}
+
+	desc := msg.ProtoReflect().Descriptor()
+	info.ProtoName = string(desc.FullName())
+	info.ProtoFiles = protoFiles(desc.ParentFile())
+	return info
+}
+
+// protoFiles 序列化 proto 文件以及它依赖的所有文件，依赖在前。
+func protoFiles(file protoreflect.FileDescriptor) [][]byte {
+	var res [][]byte
+	seen := make(map[string]struct{})
+
+	var walk func(fd protoreflect.FileDescriptor)
+	walk = func(fd protoreflect.FileDescriptor) {
+		if _, ok := seen[fd.Path()]; ok {
+			return
+		}
+		seen[fd.Path()] = struct{}{}
+
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			walk(imports.Get(i).FileDescriptor)
+		}
+
+		bs, err := proto.Marshal(protodesc.ToFileDescriptorProto(fd))
+		if err == nil {
+			res = append(res, bs)
+		}
+	}
+	walk(file)
+	return res
+}
+
+// Reflect 通过服务端的反射服务查询服务和方法，service 为空时查询全部服务。
+//
+// 服务端需要开启 WithReflection，请求固定使用 json 序列化。
+func (c *Client) Reflect(ctx context.Context, service string) ([]ServiceInfo, error) {
+	resp := &ReflectionResp{}
+	if err := c.callBuiltin(ctx, ReflectionServiceName, "ListServices", &ReflectionReq{Service: service}, resp); err != nil {
+		return nil, err
+	}
+	return resp.Services, nil
+}
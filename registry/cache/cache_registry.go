@@ -0,0 +1,357 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+)
+
+// defaultSyncTimeout 默认等待注册中心推送第一个快照的时间
+const defaultSyncTimeout = 3 * time.Second
+
+// 注册中心的订阅被关闭后重新订阅的退避时间
+const (
+	minResubscribeBackoff = 100 * time.Millisecond
+	maxResubscribeBackoff = 10 * time.Second
+)
+
+var _ registry.Registry = (*Registry)(nil)
+
+// Registry 带本地缓存的注册中心装饰器。
+//
+// 第一次查询或订阅某个服务时，通过被装饰的注册中心订阅该服务，之后的 ListServices 直接读取内存中的缓存。
+// 每次实例变更后将实例列表持久化到 snapshotDir 下的快照文件中；
+// 启动时如果在 syncTimeout 内没有收到注册中心的快照（例如 etcd 不可用），使用快照文件中的实例，
+// 注册中心恢复后再以注册中心的数据为准。
+type Registry struct {
+	backing     registry.Registry
+	snapshotDir string
+	syncTimeout time.Duration
+	onError     func(err error)
+
+	// 缓存的存储和事件推送复用内存注册中心
+	store *memory.Registry
+
+	mu       sync.Mutex
+	services map[string]*service
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	watchers  sync.WaitGroup
+}
+
+// service 单个服务的缓存状态。
+type service struct {
+	// ready 在收到注册中心的第一个快照或者加载快照文件之后关闭
+	ready chan struct{}
+	// 注册中心和快照文件都不可用时的错误，由 Registry.mu 保护
+	err error
+}
+
+type Option func(*Registry)
+
+// WithSyncTimeout 设置等待注册中心推送第一个快照的时间，超时后使用快照文件，默认 3s。
+func WithSyncTimeout(timeout time.Duration) Option {
+	return func(r *Registry) {
+		r.syncTimeout = timeout
+	}
+}
+
+// WithErrorHandler 设置读写快照文件失败时的回调。
+func WithErrorHandler(fn func(err error)) Option {
+	return func(r *Registry) {
+		r.onError = fn
+	}
+}
+
+func (r *Registry) Register(ctx context.Context, instance registry.ServiceInstance) error {
+	return r.backing.Register(ctx, instance)
+}
+
+func (r *Registry) Unregister(ctx context.Context, instance registry.ServiceInstance) error {
+	return r.backing.Unregister(ctx, instance)
+}
+
+func (r *Registry) UpdateStatus(ctx context.Context, instance registry.ServiceInstance, status registry.Status) error {
+	return r.backing.UpdateStatus(ctx, instance, status)
+}
+
+// ListServices 从缓存中获取服务的全部实例，第一次查询时等待缓存初始化完成。
+func (r *Registry) ListServices(ctx context.Context, serviceName string) ([]registry.ServiceInstance, error) {
+	s := r.service(serviceName)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.closeCh:
+		return nil, memory.ErrRegistryClosed
+	case <-s.ready:
+	}
+
+	if err := r.getErr(s); err != nil {
+		return nil, err
+	}
+	return r.store.ListServices(ctx, serviceName)
+}
+
+// Subscribe 订阅缓存的变更，ctx 取消或者 Close 时关闭返回的 channel。
+// 第一次订阅时等待缓存初始化完成，保证第一个快照事件包含注册中心或者快照文件中的实例；
+// 取消订阅不影响缓存与注册中心的同步。
+func (r *Registry) Subscribe(ctx context.Context, serviceName string) <-chan registry.Event {
+	s := r.service(serviceName)
+
+	select {
+	case <-ctx.Done():
+		ch := make(chan registry.Event)
+		close(ch)
+		return ch
+	case <-r.closeCh:
+	case <-s.ready:
+	}
+	return r.store.Subscribe(ctx, serviceName)
+}
+
+// service 获取服务的缓存状态，第一次调用时开始订阅该服务。
+func (r *Registry) service(serviceName string) *service {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.services[serviceName]; ok {
+		return s
+	}
+
+	s := &service{ready: make(chan struct{})}
+	select {
+	case <-r.closeCh:
+		// 已经关闭，不再订阅
+		return s
+	default:
+	}
+
+	r.services[serviceName] = s
+	r.watchers.Add(1)
+	go r.watch(serviceName, s)
+	return s
+}
+
+// watch 将注册中心的变更同步到缓存，直到注册中心关闭。
+// 订阅被注册中心关闭时按指数退避重新订阅，重新订阅后的第一个快照会覆盖缓存中的实例。
+func (r *Registry) watch(serviceName string, s *service) {
+	defer r.watchers.Done()
+
+	events := r.backing.Subscribe(context.Background(), serviceName)
+	backoff := minResubscribeBackoff
+
+	timer := time.NewTimer(r.syncTimeout)
+	defer timer.Stop()
+
+	ready, synced := false, false
+	markReady := func(err error) {
+		r.setErr(s, err)
+		if !ready {
+			ready = true
+			close(s.ready)
+		}
+	}
+
+	for {
+		select {
+		case <-r.closeCh:
+			return
+		case <-timer.C:
+			if !ready {
+				markReady(r.loadSnapshot(serviceName))
+			}
+		case event, ok := <-events:
+			if !ok {
+				// 还没有收到快照时直接使用快照文件，不再等待超时
+				if !ready {
+					markReady(r.loadSnapshot(serviceName))
+				}
+				if !r.sleep(backoff) {
+					return
+				}
+				backoff = min(backoff*2, maxResubscribeBackoff)
+				events = r.backing.Subscribe(context.Background(), serviceName)
+				continue
+			}
+
+			r.apply(serviceName, event)
+			if event.Type == registry.EventTypeSnapshot {
+				// 注册中心恢复后以注册中心的数据为准
+				synced = true
+				backoff = minResubscribeBackoff
+				markReady(nil)
+			}
+			if synced {
+				r.saveSnapshot(serviceName)
+			}
+		}
+	}
+}
+
+// sleep 等待 d，注册中心关闭时返回 false。
+func (r *Registry) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-r.closeCh:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (r *Registry) setErr(s *service, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.err = err
+}
+
+func (r *Registry) getErr(s *service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return s.err
+}
+
+// apply 将注册中心的事件应用到缓存，快照事件按实例计算差异。
+func (r *Registry) apply(serviceName string, event registry.Event) {
+	ctx := context.Background()
+
+	switch event.Type {
+	case registry.EventTypePut:
+		_ = r.store.Register(ctx, event.ServiceInstance)
+	case registry.EventTypeDel:
+		_ = r.store.Unregister(ctx, event.ServiceInstance)
+	case registry.EventTypeSnapshot:
+		r.replace(serviceName, event.Instances)
+	default:
+	}
+}
+
+// replace 使用 instances 替换服务在缓存中的全部实例。
+func (r *Registry) replace(serviceName string, instances []registry.ServiceInstance) {
+	ctx := context.Background()
+
+	current, _ := r.store.ListServices(ctx, serviceName)
+	existing := make(map[string]registry.ServiceInstance, len(current))
+	for _, instance := range current {
+		existing[instance.Addr] = instance
+	}
+
+	latest := make(map[string]struct{}, len(instances))
+	for _, instance := range instances {
+		latest[instance.Addr] = struct{}{}
+		if old, ok := existing[instance.Addr]; ok && reflect.DeepEqual(old, instance) {
+			continue
+		}
+		_ = r.store.Register(ctx, instance)
+	}
+	for _, instance := range current {
+		if _, ok := latest[instance.Addr]; !ok {
+			_ = r.store.Unregister(ctx, instance)
+		}
+	}
+}
+
+func (r *Registry) snapshotPath(serviceName string) string {
+	return filepath.Join(r.snapshotDir, url.PathEscape(serviceName)+".json")
+}
+
+// loadSnapshot 使用快照文件中的实例初始化缓存。
+func (r *Registry) loadSnapshot(serviceName string) error {
+	if r.snapshotDir == "" {
+		return fmt.Errorf("[easy-rpc] failed to sync service %s from registry in %s", serviceName, r.syncTimeout)
+	}
+
+	content, err := os.ReadFile(r.snapshotPath(serviceName))
+	if err != nil {
+		return fmt.Errorf("[easy-rpc] failed to sync service %s from registry and no snapshot available: %w", serviceName, err)
+	}
+
+	var instances []registry.ServiceInstance
+	if err = json.Unmarshal(content, &instances); err != nil {
+		return fmt.Errorf("[easy-rpc] failed to parse snapshot of service %s: %w", serviceName, err)
+	}
+
+	r.replace(serviceName, instances)
+	return nil
+}
+
+// saveSnapshot 将服务当前的实例持久化到快照文件，先写临时文件再重命名，避免留下不完整的快照。
+func (r *Registry) saveSnapshot(serviceName string) {
+	if r.snapshotDir == "" {
+		return
+	}
+
+	instances, err := r.store.ListServices(context.Background(), serviceName)
+	if err != nil {
+		return
+	}
+
+	if err = r.writeSnapshot(serviceName, instances); err != nil && r.onError != nil {
+		r.onError(err)
+	}
+}
+
+func (r *Registry) writeSnapshot(serviceName string, instances []registry.ServiceInstance) error {
+	content, err := json.Marshal(instances)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(r.snapshotDir, 0o755); err != nil {
+		return fmt.Errorf("[easy-rpc] failed to create snapshot dir: %w", err)
+	}
+
+	path := r.snapshotPath(serviceName)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("[easy-rpc] failed to write snapshot of service %s: %w", serviceName, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("[easy-rpc] failed to write snapshot of service %s: %w", serviceName, err)
+	}
+	return nil
+}
+
+// Close 关闭缓存以及被装饰的注册中心。
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	r.closeOnce.Do(func() {
+		close(r.closeCh)
+	})
+	r.mu.Unlock()
+
+	// 等待正在处理的事件以及快照文件写入完成
+	r.watchers.Wait()
+	_ = r.store.Close()
+	return r.backing.Close()
+}
+
+// NewRegistry 创建带本地缓存的注册中心，snapshotDir 为空时不持久化实例列表。
+func NewRegistry(backing registry.Registry, snapshotDir string, opts ...Option) *Registry {
+	r := &Registry{
+		backing:     backing,
+		snapshotDir: snapshotDir,
+		syncTimeout: defaultSyncTimeout,
+		store:       memory.NewRegistry(),
+		services:    make(map[string]*service, 4),
+		closeCh:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
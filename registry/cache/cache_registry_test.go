@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableRegistry 模拟不可用的注册中心，订阅后只有测试主动推送时才会收到事件。
+type unreachableRegistry struct {
+	events chan registry.Event
+}
+
+func newUnreachableRegistry() *unreachableRegistry {
+	return &unreachableRegistry{events: make(chan registry.Event, 8)}
+}
+
+func (u *unreachableRegistry) Register(context.Context, registry.ServiceInstance) error {
+	return errors.New("unreachable")
+}
+
+func (u *unreachableRegistry) Unregister(context.Context, registry.ServiceInstance) error {
+	return errors.New("unreachable")
+}
+
+func (u *unreachableRegistry) UpdateStatus(context.Context, registry.ServiceInstance, registry.Status) error {
+	return errors.New("unreachable")
+}
+
+func (u *unreachableRegistry) ListServices(context.Context, string) ([]registry.ServiceInstance, error) {
+	return nil, errors.New("unreachable")
+}
+
+func (u *unreachableRegistry) Subscribe(context.Context, string) <-chan registry.Event {
+	return u.events
+}
+
+func (u *unreachableRegistry) Close() error {
+	return nil
+}
+
+// flakyRegistry 可以主动关闭订阅的注册中心，模拟订阅被注册中心断开。
+type flakyRegistry struct {
+	*memory.Registry
+
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+}
+
+func (f *flakyRegistry) Subscribe(ctx context.Context, serviceName string) <-chan registry.Event {
+	ctx, cancel := context.WithCancel(ctx)
+
+	f.mu.Lock()
+	f.cancels = append(f.cancels, cancel)
+	f.mu.Unlock()
+	return f.Registry.Subscribe(ctx, serviceName)
+}
+
+// disconnect 关闭当前的全部订阅。
+func (f *flakyRegistry) disconnect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, cancel := range f.cancels {
+		cancel()
+	}
+}
+
+func (f *flakyRegistry) subscriptions() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.cancels)
+}
+
+func instance(addr string) registry.ServiceInstance {
+	return registry.ServiceInstance{Name: "user-service", Addr: addr, Weight: 10}
+}
+
+func listServices(t *testing.T, r registry.Registry) []registry.ServiceInstance {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	instances, err := r.ListServices(ctx, "user-service")
+	require.NoError(t, err)
+	return instances
+}
+
+func TestRegistry(t *testing.T) {
+	backing := memory.NewRegistry()
+	r := NewRegistry(backing, t.TempDir())
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8081")}, listServices(t, r))
+
+	ch := r.Subscribe(ctx, "user-service")
+	select {
+	case event := <-ch:
+		assert.Equal(t, registry.EventTypeSnapshot, event.Type)
+	case <-time.After(time.Second):
+		require.FailNow(t, "no snapshot received")
+	}
+
+	// 缓存通过订阅更新
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8082")))
+	select {
+	case event := <-ch:
+		assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance("127.0.0.1:8082")}, event)
+	case <-time.After(time.Second):
+		require.FailNow(t, "no put event received")
+	}
+	assert.Len(t, listServices(t, r), 2)
+
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8081")))
+	assert.Eventually(t, func() bool {
+		return len(listServices(t, r)) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistrySnapshotFallback(t *testing.T) {
+	dir := t.TempDir()
+
+	// 注册中心可用时持久化实例列表
+	backing := memory.NewRegistry()
+	ctx := context.Background()
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8081")))
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8082")))
+
+	r := NewRegistry(backing, dir)
+	assert.Len(t, listServices(t, r), 2)
+	require.NoError(t, backing.Unregister(ctx, instance("127.0.0.1:8082")))
+	assert.Eventually(t, func() bool {
+		return len(listServices(t, r)) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, r.Close())
+
+	// 注册中心不可用时使用快照文件
+	unreachable := newUnreachableRegistry()
+	r = NewRegistry(unreachable, dir, WithSyncTimeout(50*time.Millisecond))
+	defer func() { _ = r.Close() }()
+
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8081")}, listServices(t, r))
+
+	// 注册中心恢复后以注册中心的数据为准
+	unreachable.events <- registry.Event{
+		Type:      registry.EventTypeSnapshot,
+		Instances: []registry.ServiceInstance{instance("127.0.0.1:8083")},
+	}
+	assert.Eventually(t, func() bool {
+		instances := listServices(t, r)
+		return len(instances) == 1 && instances[0].Addr == "127.0.0.1:8083"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistryNoSnapshot(t *testing.T) {
+	unreachable := newUnreachableRegistry()
+	r := NewRegistry(unreachable, t.TempDir(), WithSyncTimeout(50*time.Millisecond))
+	defer func() { _ = r.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.ListServices(ctx, "user-service")
+	assert.Error(t, err)
+
+	unreachable.events <- registry.Event{
+		Type:      registry.EventTypeSnapshot,
+		Instances: []registry.ServiceInstance{instance("127.0.0.1:8081")},
+	}
+	assert.Eventually(t, func() bool {
+		instances, err := r.ListServices(ctx, "user-service")
+		return err == nil && len(instances) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistrySubscribeWaitsForSync(t *testing.T) {
+	backing := memory.NewRegistry()
+	ctx := context.Background()
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8081")))
+
+	r := NewRegistry(backing, "")
+	defer func() { _ = r.Close() }()
+
+	// 第一个快照包含注册中心中的实例
+	ch := r.Subscribe(ctx, "user-service")
+	select {
+	case event := <-ch:
+		assert.Equal(t, registry.Event{
+			Type:      registry.EventTypeSnapshot,
+			Instances: []registry.ServiceInstance{instance("127.0.0.1:8081")},
+		}, event)
+	case <-time.After(time.Second):
+		require.FailNow(t, "no snapshot received")
+	}
+
+	// ctx 取消时不再等待
+	r = NewRegistry(newUnreachableRegistry(), "", WithSyncTimeout(time.Minute))
+	defer func() { _ = r.Close() }()
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, ok := <-r.Subscribe(cancelled, "user-service")
+	assert.False(t, ok)
+}
+
+func TestRegistryResubscribe(t *testing.T) {
+	backing := &flakyRegistry{Registry: memory.NewRegistry()}
+	ctx := context.Background()
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8081")))
+
+	r := NewRegistry(backing, "")
+	defer func() { _ = r.Close() }()
+	assert.Len(t, listServices(t, r), 1)
+
+	// 订阅被关闭后重新订阅，并继续同步注册中心的变更
+	backing.disconnect()
+	require.Eventually(t, func() bool {
+		return backing.subscriptions() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8082")))
+	assert.Eventually(t, func() bool {
+		return len(listServices(t, r)) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistrySubscriptionClosedBeforeSync(t *testing.T) {
+	dir := t.TempDir()
+
+	backing := memory.NewRegistry()
+	ctx := context.Background()
+	require.NoError(t, backing.Register(ctx, instance("127.0.0.1:8081")))
+	r := NewRegistry(backing, dir)
+	assert.Len(t, listServices(t, r), 1)
+	require.NoError(t, r.Close())
+
+	// 收到快照之前订阅被关闭，直接使用快照文件，不等待同步超时
+	closed := memory.NewRegistry()
+	require.NoError(t, closed.Close())
+	r = NewRegistry(closed, dir, WithSyncTimeout(time.Minute))
+	defer func() { _ = r.Close() }()
+
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8081")}, listServices(t, r))
+}
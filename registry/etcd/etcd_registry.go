@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/JrMarcco/easy-rpc/registry"
 	"go.etcd.io/etcd/api/v3/mvccpb"
@@ -13,6 +15,12 @@ import (
 	"go.etcd.io/etcd/client/v3/concurrency"
 )
 
+// watchRetryInterval 监听出错后重试的间隔
+const watchRetryInterval = time.Second
+
+// defaultPrefix 默认的 key 前缀
+const defaultPrefix = "/easyrpc"
+
 var eventTypeMap = map[mvccpb.Event_EventType]registry.EventType{
 	mvccpb.PUT:    registry.EventTypePut,
 	mvccpb.DELETE: registry.EventTypeDel,
@@ -21,96 +29,341 @@ var eventTypeMap = map[mvccpb.Event_EventType]registry.EventType{
 var _ registry.Registry = (*Registry)(nil)
 
 type Registry struct {
-	mu          sync.Mutex
-	etcdClient  *clientv3.Client
-	etcdSession *concurrency.Session
+	etcdClient *clientv3.Client
 
 	leaseTTL int // 租约 ttl
 
-	watchCancel []context.CancelFunc
+	// key 的根路径，由前缀和命名空间组成，例如 /easyrpc/prod
+	root string
+
+	// sessionMu 保护会话以及已注册的实例，会话丢失后使用新的会话重新注册这些实例
+	sessionMu   sync.Mutex
+	etcdSession *concurrency.Session
+	registered  map[string]registry.ServiceInstance
+
+	onSessionLost     func()
+	onSessionRestored func(outage time.Duration)
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func (r *Registry) Register(ctx context.Context, instance registry.ServiceInstance) error {
+	r.sessionMu.Lock()
+	defer r.sessionMu.Unlock()
+
+	if err := r.put(ctx, r.etcdSession, instance); err != nil {
+		return err
+	}
+	r.registered[r.instanceKey(instance)] = instance
+	return nil
+}
+
+func (r *Registry) put(ctx context.Context, session *concurrency.Session, instance registry.ServiceInstance) error {
 	val, err := json.Marshal(instance)
 	if err != nil {
 		return err
 	}
-	_, err = r.etcdClient.Put(ctx, r.instanceKey(instance), string(val), clientv3.WithLease(r.etcdSession.Lease()))
+	_, err = r.etcdClient.Put(ctx, r.instanceKey(instance), string(val), clientv3.WithLease(session.Lease()))
 	return err
 }
 
 func (r *Registry) Unregister(ctx context.Context, instance registry.ServiceInstance) error {
-	_, err := r.etcdClient.Delete(ctx, r.instanceKey(instance))
-	return err
+	r.sessionMu.Lock()
+	defer r.sessionMu.Unlock()
+
+	key := r.instanceKey(instance)
+	if _, err := r.etcdClient.Delete(ctx, key); err != nil {
+		return err
+	}
+	delete(r.registered, key)
+	return nil
+}
+
+// UpdateStatus 更新实例状态。
+// 实例不是通过该注册中心注册的（例如由独立的健康检查更新状态）时，保留实例原有的租约。
+func (r *Registry) UpdateStatus(ctx context.Context, instance registry.ServiceInstance, status registry.Status) error {
+	r.sessionMu.Lock()
+	defer r.sessionMu.Unlock()
+
+	key := r.instanceKey(instance)
+	if registered, ok := r.registered[key]; ok {
+		registered.Status = status
+		if err := r.put(ctx, r.etcdSession, registered); err != nil {
+			return err
+		}
+		r.registered[key] = registered
+		return nil
+	}
+
+	resp, err := r.etcdClient.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 {
+		return fmt.Errorf("[easy-rpc] instance %s of service %s not found", instance.Addr, instance.Name)
+	}
+
+	kv := resp.Kvs[0]
+	var current registry.ServiceInstance
+	if err = json.Unmarshal(kv.Value, &current); err != nil {
+		return err
+	}
+	current.Status = status
+
+	val, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	// 实例在读取之后被删除或者修改时放弃更新
+	txn, err := r.etcdClient.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", kv.ModRevision)).
+		Then(clientv3.OpPut(key, string(val), clientv3.WithLease(clientv3.LeaseID(kv.Lease)))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txn.Succeeded {
+		return fmt.Errorf("[easy-rpc] instance %s of service %s changed concurrently", instance.Addr, instance.Name)
+	}
+	return nil
+}
+
+// keepAlive 监听会话状态，会话丢失（例如网络抖动导致租约过期）后创建新的会话，
+// 并使用新的租约重新注册所有已注册的实例，直到注册中心关闭。
+func (r *Registry) keepAlive() {
+	for {
+		r.sessionMu.Lock()
+		session := r.etcdSession
+		r.sessionMu.Unlock()
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-session.Done():
+		}
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		lostAt := time.Now()
+		if r.onSessionLost != nil {
+			r.onSessionLost()
+		}
+
+		if !r.restoreSession() {
+			return
+		}
+
+		if r.onSessionRestored != nil {
+			r.onSessionRestored(time.Since(lostAt))
+		}
+	}
+}
+
+// restoreSession 创建新的会话并重新注册所有实例，失败时重试直到成功或者注册中心关闭。
+func (r *Registry) restoreSession() bool {
+	for {
+		session, err := concurrency.NewSession(r.etcdClient, concurrency.WithTTL(r.leaseTTL))
+		if err == nil {
+			if err = r.reRegister(session); err == nil {
+				return true
+			}
+			_ = session.Close()
+		}
+
+		if !sleep(r.ctx, watchRetryInterval) {
+			return false
+		}
+	}
+}
+
+// reRegister 使用新的会话重新注册所有实例，全部成功后替换当前会话。
+func (r *Registry) reRegister(session *concurrency.Session) error {
+	r.sessionMu.Lock()
+	defer r.sessionMu.Unlock()
+
+	// 注册中心已经关闭，Close 不会再关闭这个会话
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, instance := range r.registered {
+		ctx, cancel := context.WithTimeout(r.ctx, time.Duration(r.leaseTTL)*time.Second)
+		err := r.put(ctx, session, instance)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	r.etcdSession = session
+	return nil
 }
 
 func (r *Registry) ListServices(ctx context.Context, serviceName string) ([]registry.ServiceInstance, error) {
+	instances, _, err := r.listServices(ctx, serviceName)
+	return instances, err
+}
+
+// listServices 获取服务的全部实例，同时返回读取时的 revision。
+func (r *Registry) listServices(ctx context.Context, serviceName string) ([]registry.ServiceInstance, int64, error) {
 	resp, err := r.etcdClient.Get(ctx, r.serviceKey(serviceName), clientv3.WithPrefix())
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	res := make([]registry.ServiceInstance, 0, len(resp.Kvs))
 	for _, kv := range resp.Kvs {
 		var instance registry.ServiceInstance
 		err = json.Unmarshal(kv.Value, &instance)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		res = append(res, instance)
 	}
-	return res, nil
+	return res, resp.Header.Revision, nil
 }
 
-func (r *Registry) Subscribe(serviceName string) <-chan registry.Event {
-	ctx, cancel := context.WithCancel(context.Background())
+// Subscribe 订阅服务实例变更，ctx 取消或者 Close 时关闭返回的 channel。
+func (r *Registry) Subscribe(ctx context.Context, serviceName string) <-chan registry.Event {
+	ctx, cancel := context.WithCancel(ctx)
+	// 注册中心关闭时同时取消订阅
+	stop := context.AfterFunc(r.ctx, cancel)
 	ctx = clientv3.WithRequireLeader(ctx)
 
-	r.mu.Lock()
-	r.watchCancel = append(r.watchCancel, cancel)
-	r.mu.Unlock()
-
-	watchChan := r.etcdClient.Watch(ctx, r.serviceKey(serviceName), clientv3.WithPrefix())
-
 	ch := make(chan registry.Event)
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case resp := <-watchChan:
-				if resp.Err() != nil {
-					continue
-				}
-				if resp.Canceled {
+		defer cancel()
+		defer stop()
+		r.watch(ctx, serviceName, ch)
+	}()
+
+	return ch
+}
+
+// watch 监听服务实例变更。
+//
+// 首先推送一次全量快照，之后从快照的 revision 开始监听增量变更：
+// 监听出错时从最后处理的 revision 重新监听，不会丢失中间的变更；
+// 需要的 revision 已经被压缩时，重新推送全量快照后再继续监听。
+func (r *Registry) watch(ctx context.Context, serviceName string, ch chan<- registry.Event) {
+	defer close(ch)
+
+	rev, ok := r.snapshot(ctx, serviceName, ch)
+	if !ok {
+		return
+	}
+
+	for {
+		// 每次重新监听都使用新的 ctx，保证旧的 watcher 被释放
+		watchCtx, watchCancel := context.WithCancel(ctx)
+		watchChan := r.etcdClient.Watch(
+			watchCtx, r.serviceKey(serviceName),
+			clientv3.WithPrefix(), clientv3.WithRev(rev+1), clientv3.WithPrevKV(),
+		)
+
+	recv:
+		for resp := range watchChan {
+			if resp.CompactRevision != 0 {
+				// 从 rev 开始的变更已经被压缩，只能重新同步全量快照
+				if rev, ok = r.snapshot(ctx, serviceName, ch); !ok {
+					watchCancel()
 					return
 				}
+				break recv
+			}
+			if resp.Err() != nil {
+				break recv
+			}
 
-				for _, e := range resp.Events {
-					ch <- registry.Event{Type: eventTypeMap[e.Type]}
+			for _, e := range resp.Events {
+				if !send(ctx, ch, r.toEvent(serviceName, e)) {
+					watchCancel()
+					return
 				}
+				rev = e.Kv.ModRevision
 			}
 		}
-	}()
+		watchCancel()
 
-	return ch
+		// watchChan 被关闭或者出错，从最后处理的 revision 重新监听
+		if !sleep(ctx, watchRetryInterval) {
+			return
+		}
+	}
 }
 
-func (r *Registry) Close() error {
-	r.mu.Lock()
-	for _, cancel := range r.watchCancel {
-		cancel()
+// snapshot 推送全量快照，返回快照对应的 revision，失败时重试直到订阅被取消。
+func (r *Registry) snapshot(ctx context.Context, serviceName string, ch chan<- registry.Event) (int64, bool) {
+	for {
+		instances, rev, err := r.listServices(ctx, serviceName)
+		if err == nil {
+			event := registry.Event{Type: registry.EventTypeSnapshot, Instances: instances}
+			return rev, send(ctx, ch, event)
+		}
+		if !sleep(ctx, watchRetryInterval) {
+			return 0, false
+		}
+	}
+}
+
+// toEvent 将 etcd 事件转换为 registry.Event。
+// 优先从 value 中解析服务实例，delete 事件没有 value，使用 PrevKv 或者从 key 中解析。
+func (r *Registry) toEvent(serviceName string, e *clientv3.Event) registry.Event {
+	kv := e.Kv
+	if e.Type == mvccpb.DELETE && e.PrevKv != nil {
+		kv = e.PrevKv
 	}
-	r.mu.Unlock()
 
+	var instance registry.ServiceInstance
+	if err := json.Unmarshal(kv.Value, &instance); err != nil || instance.Addr == "" {
+		instance = registry.ServiceInstance{
+			Name: serviceName,
+			Addr: strings.TrimPrefix(string(e.Kv.Key), r.serviceKey(serviceName)),
+		}
+	}
+
+	return registry.Event{
+		Type:            eventTypeMap[e.Type],
+		ServiceInstance: instance,
+	}
+}
+
+func send(ctx context.Context, ch chan<- registry.Event, event registry.Event) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- event:
+		return true
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (r *Registry) Close() error {
+	r.cancel()
+
+	r.sessionMu.Lock()
+	defer r.sessionMu.Unlock()
 	return r.etcdSession.Close()
 }
 
+// serviceKey 服务实例 key 的公共前缀，以 / 结尾，保证按前缀匹配时不会匹配到以该服务名开头的其他服务。
 func (r *Registry) serviceKey(serviceName string) string {
-	return fmt.Sprintf("/easyrpc/%s", serviceName)
+	return fmt.Sprintf("%s/%s/", r.root, serviceName)
 }
 
 func (r *Registry) instanceKey(instance registry.ServiceInstance) string {
-	return fmt.Sprintf("/easyrpc/%s/%s", instance.Name, instance.Addr)
+	return r.serviceKey(instance.Name) + instance.Addr
 }
 
 type RegistryBuilder struct {
@@ -132,6 +385,38 @@ type RegistryBuilder struct {
 	//		虽然续约频繁会增加 etcd 的 QPS，但大集群本身对注册中心的压力主要来自服务变更和查询。
 	//		但是通过合理缩短 TTL 和加快续约，可以让 etcd 更及时地维护服务列表，避免因“过期”服务过多导致的查询不准确。
 	leaseTTL int
+
+	// key 前缀，默认 /easyrpc
+	prefix string
+	// 命名空间，例如环境或者租户，不同命名空间的服务互相不可见
+	namespace string
+
+	onSessionLost     func()
+	onSessionRestored func(outage time.Duration)
+}
+
+// OnSessionLost 设置会话丢失时的回调，此时通过该注册中心注册的实例已经或者即将被 etcd 删除。
+func (rb *RegistryBuilder) OnSessionLost(fn func()) *RegistryBuilder {
+	rb.onSessionLost = fn
+	return rb
+}
+
+// OnSessionRestored 设置会话恢复时的回调，outage 为会话丢失到所有实例重新注册完成的时长。
+func (rb *RegistryBuilder) OnSessionRestored(fn func(outage time.Duration)) *RegistryBuilder {
+	rb.onSessionRestored = fn
+	return rb
+}
+
+// Prefix 设置 key 前缀，实例的 key 为 <prefix>[/<namespace>]/<service>/<addr>。
+func (rb *RegistryBuilder) Prefix(prefix string) *RegistryBuilder {
+	rb.prefix = prefix
+	return rb
+}
+
+// Namespace 设置命名空间，共用同一个 etcd 集群的不同环境或者租户使用不同的命名空间隔离。
+func (rb *RegistryBuilder) Namespace(namespace string) *RegistryBuilder {
+	rb.namespace = namespace
+	return rb
 }
 
 // LeaseTTL 设置租约 ttl，单位为秒。
@@ -145,19 +430,38 @@ func (rb *RegistryBuilder) Build() (*Registry, error) {
 		return nil, errors.New("[jotify] etcd lease TTL must be greater than 0")
 	}
 
+	root := "/" + strings.Trim(rb.prefix, "/")
+	if root == "/" {
+		return nil, errors.New("[easy-rpc] etcd key prefix must not be empty")
+	}
+	if ns := strings.Trim(rb.namespace, "/"); ns != "" {
+		root += "/" + ns
+	}
+
 	session, err := concurrency.NewSession(rb.etcdClient, concurrency.WithTTL(rb.leaseTTL))
 	if err != nil {
 		return nil, err
 	}
-	return &Registry{
-		etcdClient:  rb.etcdClient,
-		etcdSession: session,
-	}, nil
+
+	r := &Registry{
+		etcdClient:        rb.etcdClient,
+		leaseTTL:          rb.leaseTTL,
+		root:              root,
+		etcdSession:       session,
+		registered:        make(map[string]registry.ServiceInstance),
+		onSessionLost:     rb.onSessionLost,
+		onSessionRestored: rb.onSessionRestored,
+	}
+	// 会话不使用该 ctx，保证 Close 时仍然可以撤销租约
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	go r.keepAlive()
+	return r, nil
 }
 
 func NewRegistryBuilder(client *clientv3.Client) *RegistryBuilder {
 	return &RegistryBuilder{
 		etcdClient: client,
 		leaseTTL:   30,
+		prefix:     defaultPrefix,
 	}
 }
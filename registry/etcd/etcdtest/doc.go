@@ -0,0 +1,5 @@
+// Package etcdtest 使用进程内启动的 etcd 测试 etcd 注册中心。
+//
+// 测试依赖 go.etcd.io/etcd/server，为了不让使用 easy-rpc 的项目引入 etcd server 的依赖，
+// 测试放在独立的 module 中，需要在本目录下运行 go test ./...。
+package etcdtest
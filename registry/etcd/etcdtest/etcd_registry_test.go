@@ -0,0 +1,411 @@
+package etcdtest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/JrMarcco/easy-rpc/registry/etcd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// testEtcd 进程内启动的 etcd，重启时复用同一个数据目录和端口。
+type testEtcd struct {
+	t   *testing.T
+	cfg *embed.Config
+	e   *embed.Etcd
+}
+
+func freeURL(t *testing.T) url.URL {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	return url.URL{Scheme: "http", Host: ln.Addr().String()}
+}
+
+func startEtcd(t *testing.T) *testEtcd {
+	t.Helper()
+
+	cfg := embed.NewConfig()
+	cfg.Name = "easyrpc-test"
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg.ListenClientUrls = []url.URL{clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{clientURL}
+	cfg.ListenPeerUrls = []url.URL{peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	te := &testEtcd{t: t, cfg: cfg}
+	te.start()
+	t.Cleanup(te.stop)
+	return te
+}
+
+func (te *testEtcd) start() {
+	te.t.Helper()
+
+	e, err := embed.StartEtcd(te.cfg)
+	require.NoError(te.t, err)
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		e.Close()
+		require.FailNow(te.t, "etcd server took too long to start")
+	}
+	te.e = e
+}
+
+func (te *testEtcd) stop() {
+	if te.e != nil {
+		te.e.Close()
+		te.e = nil
+	}
+}
+
+// restart 重启 etcd，关闭时会等待已有的 watch 流结束，耗时数秒。
+func (te *testEtcd) restart() {
+	te.stop()
+	// 重启后成员已经存在于数据目录中
+	te.cfg.ClusterState = embed.ClusterStateFlagExisting
+	te.start()
+}
+
+func (te *testEtcd) client() *clientv3.Client {
+	te.t.Helper()
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{te.cfg.ListenClientUrls[0].Host},
+		DialTimeout: 5 * time.Second,
+	})
+	require.NoError(te.t, err)
+	te.t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func newTestRegistry(t *testing.T, client *clientv3.Client, ttl int) *etcd.Registry {
+	t.Helper()
+
+	r, err := etcd.NewRegistryBuilder(client).LeaseTTL(ttl).Build()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func instance(addr string) registry.ServiceInstance {
+	return registry.ServiceInstance{
+		Name:   "user-service",
+		Addr:   addr,
+		Group:  "canary",
+		Weight: 10,
+		Labels: map[string]string{"env": "test"},
+	}
+}
+
+// lease 查询实例 key 当前绑定的租约。
+func lease(t *testing.T, client *clientv3.Client, key string) clientv3.LeaseID {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Get(ctx, key)
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	return clientv3.LeaseID(resp.Kvs[0].Lease)
+}
+
+func recv(t *testing.T, ch <-chan registry.Event, timeout time.Duration) registry.Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return event
+	case <-time.After(timeout):
+		require.FailNow(t, "no event received")
+		return registry.Event{}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	te := startEtcd(t)
+	r := newTestRegistry(t, te.client(), 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+	// 服务名是 user-service 的前缀
+	user := registry.ServiceInstance{Name: "user", Addr: "127.0.0.1:9091"}
+	require.NoError(t, r.Register(ctx, user))
+
+	instances, err := r.ListServices(ctx, "user-service")
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8081"), instance("127.0.0.1:8082")}, instances)
+
+	instances, err = r.ListServices(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{user}, instances)
+
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8081")))
+
+	instances, err = r.ListServices(ctx, "user-service")
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8082")}, instances)
+
+	instances, err = r.ListServices(ctx, "order-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestRegistryNamespace(t *testing.T) {
+	te := startEtcd(t)
+	client := te.client()
+
+	build := func(rb *etcd.RegistryBuilder) *etcd.Registry {
+		r, err := rb.Build()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = r.Close() })
+		return r
+	}
+	prod := build(etcd.NewRegistryBuilder(client).Namespace("prod"))
+	test := build(etcd.NewRegistryBuilder(client).Namespace("test"))
+	custom := build(etcd.NewRegistryBuilder(client).Prefix("/services/").Namespace("prod"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, prod.Register(ctx, instance("127.0.0.1:8081")))
+	require.NoError(t, test.Register(ctx, instance("127.0.0.1:8082")))
+	require.NoError(t, custom.Register(ctx, instance("127.0.0.1:8083")))
+
+	tcs := []struct {
+		name     string
+		r        *etcd.Registry
+		wantAddr string
+	}{
+		{name: "prod", r: prod, wantAddr: "127.0.0.1:8081"},
+		{name: "test", r: test, wantAddr: "127.0.0.1:8082"},
+		{name: "custom prefix", r: custom, wantAddr: "127.0.0.1:8083"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			instances, err := tc.r.ListServices(ctx, "user-service")
+			require.NoError(t, err)
+			assert.Equal(t, []registry.ServiceInstance{instance(tc.wantAddr)}, instances)
+		})
+	}
+
+	resp, err := client.Get(ctx, "/services/prod/user-service/127.0.0.1:8083")
+	require.NoError(t, err)
+	assert.Len(t, resp.Kvs, 1)
+
+	_, err = etcd.NewRegistryBuilder(client).Prefix("/").Build()
+	assert.Error(t, err)
+}
+
+func TestRegistrySubscribe(t *testing.T) {
+	te := startEtcd(t)
+	r := newTestRegistry(t, te.client(), 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	// 不会收到以 user-service 为前缀的其他服务的事件
+	require.NoError(t, r.Register(ctx, registry.ServiceInstance{Name: "user-service-admin", Addr: "127.0.0.1:9091"}))
+
+	ch := r.Subscribe(context.Background(), "user-service")
+	assert.Equal(t, registry.Event{
+		Type:      registry.EventTypeSnapshot,
+		Instances: []registry.ServiceInstance{instance("127.0.0.1:8081")},
+	}, recv(t, ch, time.Second))
+
+	updated := instance("127.0.0.1:8081")
+	updated.Weight = 20
+	require.NoError(t, r.Register(ctx, registry.ServiceInstance{Name: "user-service-admin", Addr: "127.0.0.1:9092"}))
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+	require.NoError(t, r.Register(ctx, updated))
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8082")))
+
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance("127.0.0.1:8082")}, recv(t, ch, time.Second))
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: updated}, recv(t, ch, time.Second))
+	// del 事件通过 PrevKv 携带完整的实例
+	assert.Equal(t, registry.Event{Type: registry.EventTypeDel, ServiceInstance: instance("127.0.0.1:8082")}, recv(t, ch, time.Second))
+}
+
+func TestRegistryUpdateStatus(t *testing.T) {
+	te := startEtcd(t)
+	client := te.client()
+	r := newTestRegistry(t, client, 30)
+	checker := newTestRegistry(t, client, 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	leaseID := lease(t, client, "/easyrpc/user-service/127.0.0.1:8081")
+
+	ch := r.Subscribe(context.Background(), "user-service")
+	assert.Len(t, recv(t, ch, time.Second).Instances, 1)
+
+	want := instance("127.0.0.1:8081")
+	want.Status = registry.StatusDraining
+	require.NoError(t, r.UpdateStatus(ctx, instance("127.0.0.1:8081"), registry.StatusDraining))
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: want}, recv(t, ch, time.Second))
+
+	// 由其他注册中心（例如健康检查）更新状态时保留原有的租约
+	want.Status = registry.StatusUnhealthy
+	require.NoError(t, checker.UpdateStatus(ctx, instance("127.0.0.1:8081"), registry.StatusUnhealthy))
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: want}, recv(t, ch, time.Second))
+
+	assert.Equal(t, leaseID, lease(t, client, "/easyrpc/user-service/127.0.0.1:8081"))
+
+	assert.Error(t, checker.UpdateStatus(ctx, instance("127.0.0.1:8082"), registry.StatusUnhealthy))
+}
+
+func TestRegistryLeaseExpire(t *testing.T) {
+	te := startEtcd(t)
+	client := te.client()
+
+	lost := make(chan struct{}, 1)
+	restored := make(chan time.Duration, 1)
+	r, err := etcd.NewRegistryBuilder(client).
+		LeaseTTL(1).
+		OnSessionLost(func() { lost <- struct{}{} }).
+		OnSessionRestored(func(outage time.Duration) { restored <- outage }).
+		Build()
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+
+	watcher := newTestRegistry(t, client, 30)
+	ch := watcher.Subscribe(context.Background(), "user-service")
+	assert.Len(t, recv(t, ch, time.Second).Instances, 1)
+
+	// 撤销租约，模拟会话丢失后租约过期
+	leaseID := lease(t, client, "/easyrpc/user-service/127.0.0.1:8081")
+	_, err = client.Revoke(ctx, leaseID)
+	require.NoError(t, err)
+
+	// 租约过期后实例被删除，会话恢复后重新注册
+	assert.Equal(t, registry.Event{Type: registry.EventTypeDel, ServiceInstance: instance("127.0.0.1:8081")}, recv(t, ch, 5*time.Second))
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance("127.0.0.1:8081")}, recv(t, ch, 5*time.Second))
+
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		require.FailNow(t, "session lost not reported")
+	}
+	select {
+	case outage := <-restored:
+		assert.Greater(t, outage, time.Duration(0))
+	case <-time.After(time.Second):
+		require.FailNow(t, "session restored not reported")
+	}
+
+	restoredID := lease(t, client, "/easyrpc/user-service/127.0.0.1:8081")
+	assert.NotEqual(t, leaseID, restoredID)
+
+	// 新的租约持续续约，实例不会再过期
+	time.Sleep(3 * time.Second)
+	instances, err := watcher.ListServices(ctx, "user-service")
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8081")}, instances)
+
+	// 注销的实例不会在会话恢复后重新注册
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8081")))
+	assert.Equal(t, registry.EventTypeDel, recv(t, ch, time.Second).Type)
+
+	_, err = client.Revoke(ctx, restoredID)
+	require.NoError(t, err)
+	select {
+	case <-restored:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "session restored not reported")
+	}
+	instances, err = watcher.ListServices(ctx, "user-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestRegistryCloseRevokesLease(t *testing.T) {
+	te := startEtcd(t)
+	client := te.client()
+
+	r, err := etcd.NewRegistryBuilder(client).Build()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	require.NoError(t, r.Close())
+
+	// 关闭时撤销租约，实例立即被删除
+	resp, err := client.Get(ctx, "/easyrpc/user-service", clientv3.WithPrefix(), clientv3.WithCountOnly())
+	require.NoError(t, err)
+	assert.Zero(t, resp.Count)
+}
+
+func TestRegistryWatchRecover(t *testing.T) {
+	te := startEtcd(t)
+	r := newTestRegistry(t, te.client(), 30)
+
+	ch := r.Subscribe(context.Background(), "user-service")
+	assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch, time.Second).Type)
+
+	te.restart()
+
+	// etcd 重启后，重新连接的客户端写入的变更仍然能被监听到
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	other := newTestRegistry(t, te.client(), 30)
+	require.NoError(t, other.Register(ctx, instance("127.0.0.1:8081")))
+
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance("127.0.0.1:8081")}, recv(t, ch, 10*time.Second))
+}
+
+func TestRegistryClose(t *testing.T) {
+	te := startEtcd(t)
+
+	r, err := etcd.NewRegistryBuilder(te.client()).Build()
+	require.NoError(t, err)
+
+	chs := make([]<-chan registry.Event, 0, 3)
+	for i := range 3 {
+		ch := r.Subscribe(context.Background(), fmt.Sprintf("service-%d", i))
+		assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch, time.Second).Type)
+		chs = append(chs, ch)
+	}
+
+	require.NoError(t, r.Close())
+
+	for _, ch := range chs {
+		select {
+		case _, ok := <-ch:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			require.FailNow(t, "watcher not cancelled")
+		}
+	}
+}
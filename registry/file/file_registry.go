@@ -0,0 +1,249 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/JrMarcco/easy-rpc/registry/memory"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPollInterval 默认检查文件变更的间隔
+const defaultPollInterval = time.Second
+
+var ErrReadOnly = errors.New("[easy-rpc] file registry is read-only")
+
+var _ registry.Registry = (*Registry)(nil)
+
+// Registry 基于文件的静态注册中心，用于本地开发以及没有 etcd 的环境。
+//
+// 文件格式为 YAML 或 JSON（按扩展名区分，.json 为 JSON，其他为 YAML）：
+//
+//	services:
+//	  user-service:
+//	    - addr: 127.0.0.1:8081
+//	      group: canary
+//	      weight: 10
+//	      status: draining
+//	      labels:
+//	        env: dev
+//
+// 定期检查文件内容，变更时按实例推送 put/del 事件；文件无法解析或者校验失败时保留上一次的有效内容。
+// 实例只能通过修改文件变更，Register、Unregister 和 UpdateStatus 返回 ErrReadOnly。
+type Registry struct {
+	path         string
+	pollInterval time.Duration
+	onError      func(err error)
+
+	// 实例的存储和事件推送复用内存注册中心
+	store *memory.Registry
+
+	// 上一次有效的文件内容
+	content   []byte
+	instances map[string]registry.ServiceInstance
+	// 上一次无效的文件内容，避免重复报告同一个错误
+	invalid []byte
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	done      chan struct{}
+}
+
+type Option func(*Registry)
+
+// WithPollInterval 设置检查文件变更的间隔，默认 1s，必须大于 0。
+func WithPollInterval(interval time.Duration) Option {
+	return func(r *Registry) {
+		r.pollInterval = interval
+	}
+}
+
+// WithErrorHandler 设置重新加载文件失败时的回调，此时仍然使用上一次的有效内容。
+func WithErrorHandler(fn func(err error)) Option {
+	return func(r *Registry) {
+		r.onError = fn
+	}
+}
+
+type fileConfig struct {
+	Services map[string][]fileInstance `json:"services" yaml:"services"`
+}
+
+type fileInstance struct {
+	Addr    string            `json:"addr" yaml:"addr"`
+	Group   string            `json:"group" yaml:"group"`
+	Weight  uint32            `json:"weight" yaml:"weight"`
+	Version string            `json:"version" yaml:"version"`
+	Zone    string            `json:"zone" yaml:"zone"`
+	Region  string            `json:"region" yaml:"region"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+	Status  registry.Status   `json:"status" yaml:"status"`
+}
+
+func (r *Registry) Register(context.Context, registry.ServiceInstance) error {
+	return ErrReadOnly
+}
+
+func (r *Registry) Unregister(context.Context, registry.ServiceInstance) error {
+	return ErrReadOnly
+}
+
+func (r *Registry) UpdateStatus(context.Context, registry.ServiceInstance, registry.Status) error {
+	return ErrReadOnly
+}
+
+func (r *Registry) ListServices(ctx context.Context, serviceName string) ([]registry.ServiceInstance, error) {
+	return r.store.ListServices(ctx, serviceName)
+}
+
+// Subscribe 订阅服务实例变更，ctx 取消或者 Close 时关闭返回的 channel。
+func (r *Registry) Subscribe(ctx context.Context, serviceName string) <-chan registry.Event {
+	return r.store.Subscribe(ctx, serviceName)
+}
+
+func (r *Registry) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closeCh)
+	})
+	<-r.done
+	return r.store.Close()
+}
+
+// poll 定期检查文件变更，直到注册中心关闭。
+func (r *Registry) poll() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.closeCh:
+			return
+		case <-ticker.C:
+			if err := r.reload(); err != nil && r.onError != nil {
+				r.onError(err)
+			}
+		}
+	}
+}
+
+// reload 重新加载文件，并将实例的变更应用到 store。
+func (r *Registry) reload() error {
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("[easy-rpc] failed to read registry file %s: %w", r.path, err)
+	}
+	if bytes.Equal(content, r.content) || bytes.Equal(content, r.invalid) {
+		return nil
+	}
+
+	instances, err := parse(r.path, content)
+	if err != nil {
+		r.invalid = content
+		return err
+	}
+	r.invalid = nil
+
+	ctx := context.Background()
+	for key, old := range r.instances {
+		if _, ok := instances[key]; !ok {
+			if err = r.store.Unregister(ctx, old); err != nil {
+				return err
+			}
+		}
+	}
+	for key, instance := range instances {
+		if old, ok := r.instances[key]; ok && reflect.DeepEqual(old, instance) {
+			continue
+		}
+		if err = r.store.Register(ctx, instance); err != nil {
+			return err
+		}
+	}
+
+	r.content = content
+	r.instances = instances
+	return nil
+}
+
+// parse 解析并校验文件内容，返回以 服务名/地址 为 key 的全部实例。
+func parse(path string, content []byte) (map[string]registry.ServiceInstance, error) {
+	var cfg fileConfig
+	var err error
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(content, &cfg)
+	} else {
+		err = yaml.Unmarshal(content, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[easy-rpc] failed to parse registry file %s: %w", path, err)
+	}
+
+	instances := make(map[string]registry.ServiceInstance, len(cfg.Services))
+	for name, fis := range cfg.Services {
+		if name == "" {
+			return nil, fmt.Errorf("[easy-rpc] invalid registry file %s: empty service name", path)
+		}
+		for _, fi := range fis {
+			if _, _, err = net.SplitHostPort(fi.Addr); err != nil {
+				return nil, fmt.Errorf("[easy-rpc] invalid registry file %s: invalid address %q of service %s: %w", path, fi.Addr, name, err)
+			}
+
+			key := name + "/" + fi.Addr
+			if _, ok := instances[key]; ok {
+				return nil, fmt.Errorf("[easy-rpc] invalid registry file %s: duplicate address %s of service %s", path, fi.Addr, name)
+			}
+			instances[key] = registry.ServiceInstance{
+				Name:    name,
+				Addr:    fi.Addr,
+				Group:   fi.Group,
+				Weight:  fi.Weight,
+				Version: fi.Version,
+				Zone:    fi.Zone,
+				Region:  fi.Region,
+				Labels:  fi.Labels,
+				Status:  fi.Status,
+			}
+		}
+	}
+	return instances, nil
+}
+
+// NewRegistry 加载注册中心文件，文件无法读取或者校验失败时返回错误。
+func NewRegistry(path string, opts ...Option) (*Registry, error) {
+	r := &Registry{
+		path:         path,
+		pollInterval: defaultPollInterval,
+		store:        memory.NewRegistry(),
+		instances:    make(map[string]registry.ServiceInstance),
+		closeCh:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.pollInterval <= 0 {
+		_ = r.store.Close()
+		return nil, fmt.Errorf("[easy-rpc] invalid poll interval %s", r.pollInterval)
+	}
+
+	if err := r.reload(); err != nil {
+		_ = r.store.Close()
+		return nil, err
+	}
+
+	go r.poll()
+	return r, nil
+}
@@ -0,0 +1,224 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile 先写临时文件再重命名，避免读到写了一半的文件。
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func recv(t *testing.T, ch <-chan registry.Event) registry.Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return event
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+		return registry.Event{}
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	tcs := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+		want    []registry.ServiceInstance
+	}{
+		{
+			name: "yaml",
+			file: "registry.yaml",
+			content: `
+services:
+  user-service:
+    - addr: 127.0.0.1:8082
+      group: canary
+      weight: 10
+      status: draining
+      labels:
+        env: dev
+    - addr: 127.0.0.1:8081
+`,
+			want: []registry.ServiceInstance{
+				{Name: "user-service", Addr: "127.0.0.1:8081"},
+				{
+					Name:   "user-service",
+					Addr:   "127.0.0.1:8082",
+					Group:  "canary",
+					Weight: 10,
+					Labels: map[string]string{"env": "dev"},
+					Status: registry.StatusDraining,
+				},
+			},
+		}, {
+			name:    "json",
+			file:    "registry.json",
+			content: `{"services": {"user-service": [{"addr": "127.0.0.1:8081", "version": "1.2.0"}]}}`,
+			want: []registry.ServiceInstance{
+				{Name: "user-service", Addr: "127.0.0.1:8081", Version: "1.2.0"},
+			},
+		}, {
+			name:    "invalid yaml",
+			file:    "registry.yaml",
+			content: "services: [",
+			wantErr: true,
+		}, {
+			name: "invalid address",
+			file: "registry.yaml",
+			content: `
+services:
+  user-service:
+    - addr: 127.0.0.1
+`,
+			wantErr: true,
+		}, {
+			name: "invalid status",
+			file: "registry.yaml",
+			content: `
+services:
+  user-service:
+    - addr: 127.0.0.1:8081
+      status: stopped
+`,
+			wantErr: true,
+		}, {
+			name: "duplicate address",
+			file: "registry.yaml",
+			content: `
+services:
+  user-service:
+    - addr: 127.0.0.1:8081
+    - addr: 127.0.0.1:8081
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			writeFile(t, path, tc.content)
+
+			r, err := NewRegistry(path)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer func() { _ = r.Close() }()
+
+			instances, err := r.ListServices(context.Background(), "user-service")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, instances)
+		})
+	}
+
+	_, err := NewRegistry(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	writeFile(t, path, "services: {}")
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err = NewRegistry(path, WithPollInterval(interval))
+		assert.Error(t, err)
+	}
+}
+
+func TestRegistryReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	writeFile(t, path, "services: {}")
+
+	r, err := NewRegistry(path)
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	instance := registry.ServiceInstance{Name: "user-service", Addr: "127.0.0.1:8081"}
+	assert.ErrorIs(t, r.Register(context.Background(), instance), ErrReadOnly)
+	assert.ErrorIs(t, r.Unregister(context.Background(), instance), ErrReadOnly)
+	assert.ErrorIs(t, r.UpdateStatus(context.Background(), instance, registry.StatusDraining), ErrReadOnly)
+}
+
+func TestRegistryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.yaml")
+	writeFile(t, path, `
+services:
+  user-service:
+    - addr: 127.0.0.1:8081
+    - addr: 127.0.0.1:8082
+`)
+
+	errCh := make(chan error, 8)
+	r, err := NewRegistry(path,
+		WithPollInterval(10*time.Millisecond),
+		WithErrorHandler(func(err error) {
+			select {
+			case errCh <- err:
+			default:
+			}
+		}),
+	)
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	ch := r.Subscribe(context.Background(), "user-service")
+	assert.Len(t, recv(t, ch).Instances, 2)
+
+	// 删除 8081，修改 8082 的权重，新增 8083
+	writeFile(t, path, `
+services:
+  user-service:
+    - addr: 127.0.0.1:8082
+      weight: 5
+    - addr: 127.0.0.1:8083
+`)
+
+	events := map[string]registry.Event{}
+	for range 3 {
+		event := recv(t, ch)
+		events[event.ServiceInstance.Addr] = event
+	}
+	assert.Equal(t, registry.EventTypeDel, events["127.0.0.1:8081"].Type)
+	assert.Equal(t, registry.EventTypePut, events["127.0.0.1:8082"].Type)
+	assert.Equal(t, uint32(5), events["127.0.0.1:8082"].ServiceInstance.Weight)
+	assert.Equal(t, registry.EventTypePut, events["127.0.0.1:8083"].Type)
+
+	// 文件无法解析时保留上一次的有效内容
+	writeFile(t, path, "services: [")
+	select {
+	case err = <-errCh:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "no reload error reported")
+	}
+
+	instances, err := r.ListServices(context.Background(), "user-service")
+	require.NoError(t, err)
+	assert.Len(t, instances, 2)
+
+	// 恢复后继续推送变更
+	writeFile(t, path, `
+services:
+  user-service:
+    - addr: 127.0.0.1:8083
+`)
+	assert.Equal(t, registry.Event{
+		Type:            registry.EventTypeDel,
+		ServiceInstance: registry.ServiceInstance{Name: "user-service", Addr: "127.0.0.1:8082", Weight: 5},
+	}, recv(t, ch))
+}
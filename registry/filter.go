@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter 服务实例过滤器，返回 true 表示保留该实例。
+type Filter func(instance ServiceInstance) bool
+
+// ParseFilter 解析过滤表达式，表达式格式为 "<field> <op> <value>"，例如 "version >= 2"、"zone == eu-1"。
+//
+// field 可以是 name、addr、group、version、zone、region、weight、status，其余的 field 视为 Labels 中的 key。
+// op 支持 ==、!=、>、>=、<、<=。比较时按 "." 分段，两边都是数字的分段按数字比较，否则按字符串比较，
+// 因此 "1.10.0" > "1.9.0"，"weight > 5" 也能按数字比较。
+// 实例没有对应的 label 时，除了 != 之外的表达式都不匹配。
+func ParseFilter(expr string) (Filter, error) {
+	index := strings.IndexAny(expr, "=!<>")
+	if index <= 0 {
+		return nil, fmt.Errorf("[easy-rpc] invalid filter expression %q", expr)
+	}
+
+	op := expr[index : index+1]
+	if index+1 < len(expr) && expr[index+1] == '=' {
+		op = expr[index : index+2]
+	}
+
+	field := strings.TrimSpace(expr[:index])
+	value := strings.TrimSpace(expr[index+len(op):])
+
+	var match func(cmp int) bool
+	switch op {
+	case "==":
+		match = func(cmp int) bool { return cmp == 0 }
+	case "!=":
+		match = func(cmp int) bool { return cmp != 0 }
+	case ">":
+		match = func(cmp int) bool { return cmp > 0 }
+	case ">=":
+		match = func(cmp int) bool { return cmp >= 0 }
+	case "<":
+		match = func(cmp int) bool { return cmp < 0 }
+	case "<=":
+		match = func(cmp int) bool { return cmp <= 0 }
+	default:
+		return nil, fmt.Errorf("[easy-rpc] invalid operator %q in filter expression %q", op, expr)
+	}
+	if field == "" || value == "" {
+		return nil, fmt.Errorf("[easy-rpc] invalid filter expression %q", expr)
+	}
+
+	return func(instance ServiceInstance) bool {
+		actual, ok := fieldValue(instance, field)
+		if !ok {
+			return op == "!="
+		}
+		return match(compareValue(actual, value))
+	}, nil
+}
+
+// MustParseFilter 解析过滤表达式，表达式不合法时 panic。
+func MustParseFilter(expr string) Filter {
+	filter, err := ParseFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return filter
+}
+
+func fieldValue(instance ServiceInstance, field string) (string, bool) {
+	switch field {
+	case "name":
+		return instance.Name, true
+	case "addr":
+		return instance.Addr, true
+	case "group":
+		if instance.Group == "" {
+			return DefaultGroup, true
+		}
+		return instance.Group, true
+	case "version":
+		return instance.Version, true
+	case "zone":
+		return instance.Zone, true
+	case "region":
+		return instance.Region, true
+	case "weight":
+		return strconv.FormatUint(uint64(instance.Weight), 10), true
+	case "status":
+		return instance.Status.String(), true
+	default:
+		val, ok := instance.Labels[field]
+		return val, ok
+	}
+}
+
+// compareValue 按 "." 分段比较两个值。
+func compareValue(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if cmp := compareSegment(as[i], bs[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return len(as) - len(bs)
+}
+
+func compareSegment(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	if aErr != nil || bErr != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case an < bn:
+		return -1
+	case an > bn:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	instance := ServiceInstance{
+		Name:    "user-service",
+		Addr:    "127.0.0.1:8081",
+		Weight:  10,
+		Version: "1.10.0",
+		Zone:    "eu-1",
+		Labels: map[string]string{
+			"env": "prod",
+		},
+	}
+
+	tcs := []struct {
+		name    string
+		expr    string
+		wantErr bool
+		want    bool
+	}{
+		{name: "equal", expr: "zone == eu-1", want: true},
+		{name: "not equal", expr: "zone != eu-1", want: false},
+		{name: "without space", expr: "zone==eu-1", want: true},
+		{name: "version greater", expr: "version >= 2", want: false},
+		{name: "version compare by segment", expr: "version > 1.9.0", want: true},
+		{name: "version prefix", expr: "version >= 1.10", want: true},
+		{name: "weight", expr: "weight > 5", want: true},
+		{name: "status", expr: "status == serving", want: true},
+		{name: "default group", expr: "group == default", want: true},
+		{name: "label", expr: "env == prod", want: true},
+		{name: "missing label", expr: "canary == true", want: false},
+		{name: "missing label not equal", expr: "canary != true", want: true},
+		{name: "without operator", expr: "zone", wantErr: true},
+		{name: "invalid operator", expr: "zone = eu-1", wantErr: true},
+		{name: "without field", expr: "== eu-1", wantErr: true},
+		{name: "without value", expr: "zone ==", wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := ParseFilter(tc.expr)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, filter(instance))
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+)
+
+var ErrRegistryClosed = errors.New("[easy-rpc] memory registry closed")
+
+var _ registry.Registry = (*Registry)(nil)
+
+// Registry 基于内存的注册中心，用于测试以及单进程部署。
+//
+// 语义与 etcd 注册中心保持一致：订阅后首先推送全量快照，之后推送增量的 put/del 事件。
+// 另外支持模拟租约过期（WithTTL、Expire）以及故障注入（SetFault），方便对依赖服务发现的逻辑做封闭测试。
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]map[string]*entry
+	subs     map[string][]*subscriber
+	fault    Fault
+	closed   bool
+
+	// 实例的存活时间，为 0 时永不过期
+	ttl     time.Duration
+	now     func() time.Time
+	closeCh chan struct{}
+}
+
+type entry struct {
+	instance registry.ServiceInstance
+	expireAt time.Time
+}
+
+// Fault 故障注入配置。
+type Fault struct {
+	// Drop 返回 true 时丢弃该事件，不推送给订阅者，调用时持有注册中心的锁，不能再调用注册中心的方法
+	Drop func(serviceName string, event registry.Event) bool
+	// Delay 事件推送的延迟，事件之间的顺序保持不变
+	Delay time.Duration
+}
+
+type Option func(*Registry)
+
+// WithTTL 设置实例的存活时间，超过 ttl 没有续约（Register 或 Renew）的实例会被删除并推送 del 事件，
+// 用于模拟 etcd 的租约过期。
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Registry) {
+		r.ttl = ttl
+	}
+}
+
+// WithFault 设置初始的故障注入配置。
+func WithFault(fault Fault) Option {
+	return func(r *Registry) {
+		r.fault = fault
+	}
+}
+
+func (r *Registry) Register(_ context.Context, instance registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrRegistryClosed
+	}
+
+	instances, ok := r.services[instance.Name]
+	if !ok {
+		instances = make(map[string]*entry, 4)
+		r.services[instance.Name] = instances
+	}
+	instances[instance.Addr] = &entry{
+		instance: instance,
+		expireAt: r.expireAt(),
+	}
+
+	r.publish(instance.Name, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance})
+	return nil
+}
+
+func (r *Registry) Unregister(_ context.Context, instance registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrRegistryClosed
+	}
+	r.remove(instance.Name, instance.Addr)
+	return nil
+}
+
+func (r *Registry) UpdateStatus(_ context.Context, instance registry.ServiceInstance, status registry.Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrRegistryClosed
+	}
+
+	e, ok := r.services[instance.Name][instance.Addr]
+	if !ok {
+		return fmt.Errorf("[easy-rpc] instance %s of service %s not found", instance.Addr, instance.Name)
+	}
+	e.instance.Status = status
+	r.publish(instance.Name, registry.Event{Type: registry.EventTypePut, ServiceInstance: e.instance})
+	return nil
+}
+
+// Renew 续约实例，实例不存在时返回错误。
+func (r *Registry) Renew(instance registry.ServiceInstance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrRegistryClosed
+	}
+
+	e, ok := r.services[instance.Name][instance.Addr]
+	if !ok {
+		return fmt.Errorf("[easy-rpc] instance %s of service %s not found", instance.Addr, instance.Name)
+	}
+	e.expireAt = r.expireAt()
+	return nil
+}
+
+// Expire 立即删除实例并推送 del 事件，模拟租约过期。
+func (r *Registry) Expire(instance registry.ServiceInstance) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(instance.Name, instance.Addr)
+}
+
+// remove 删除实例并推送 del 事件，调用方需要持有 r.mu。
+func (r *Registry) remove(serviceName string, addr string) {
+	instances := r.services[serviceName]
+	e, ok := instances[addr]
+	if !ok {
+		return
+	}
+
+	delete(instances, addr)
+	if len(instances) == 0 {
+		delete(r.services, serviceName)
+	}
+	r.publish(serviceName, registry.Event{Type: registry.EventTypeDel, ServiceInstance: e.instance})
+}
+
+func (r *Registry) ListServices(_ context.Context, serviceName string) ([]registry.ServiceInstance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrRegistryClosed
+	}
+	return r.list(serviceName), nil
+}
+
+// list 按地址排序返回服务的全部实例，调用方需要持有 r.mu。
+func (r *Registry) list(serviceName string) []registry.ServiceInstance {
+	instances := r.services[serviceName]
+	res := make([]registry.ServiceInstance, 0, len(instances))
+	for _, e := range instances {
+		res = append(res, e.instance)
+	}
+	slices.SortFunc(res, func(a, b registry.ServiceInstance) int {
+		return strings.Compare(a.Addr, b.Addr)
+	})
+	return res
+}
+
+// Subscribe 订阅服务实例变更，ctx 取消或者 Close 时关闭返回的 channel。
+func (r *Registry) Subscribe(ctx context.Context, serviceName string) <-chan registry.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub := newSubscriber()
+	if r.closed {
+		close(sub.ch)
+		return sub.ch
+	}
+
+	r.subs[serviceName] = append(r.subs[serviceName], sub)
+	go sub.run()
+	context.AfterFunc(ctx, func() { r.unsubscribe(serviceName, sub) })
+
+	r.publishTo(sub, serviceName, registry.Event{Type: registry.EventTypeSnapshot, Instances: r.list(serviceName)})
+	return sub.ch
+}
+
+// unsubscribe 取消订阅，关闭订阅者的 channel。
+func (r *Registry) unsubscribe(serviceName string, sub *subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs := slices.DeleteFunc(r.subs[serviceName], func(s *subscriber) bool { return s == sub })
+	if len(subs) == 0 {
+		delete(r.subs, serviceName)
+	} else {
+		r.subs[serviceName] = subs
+	}
+	sub.close()
+}
+
+// Snapshot 向服务的所有订阅者重新推送全量快照，模拟注册中心的重新同步。
+func (r *Registry) Snapshot(serviceName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.publish(serviceName, registry.Event{Type: registry.EventTypeSnapshot, Instances: r.list(serviceName)})
+}
+
+// SetFault 设置故障注入配置，零值表示关闭故障注入。
+func (r *Registry) SetFault(fault Fault) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fault = fault
+}
+
+// publish 推送事件给服务的所有订阅者，调用方需要持有 r.mu。
+func (r *Registry) publish(serviceName string, event registry.Event) {
+	for _, sub := range r.subs[serviceName] {
+		r.publishTo(sub, serviceName, event)
+	}
+}
+
+func (r *Registry) publishTo(sub *subscriber, serviceName string, event registry.Event) {
+	if r.fault.Drop != nil && r.fault.Drop(serviceName, event) {
+		return
+	}
+	sub.push(event, time.Now().Add(r.fault.Delay))
+}
+
+func (r *Registry) expireAt() time.Time {
+	if r.ttl <= 0 {
+		return time.Time{}
+	}
+	return r.now().Add(r.ttl)
+}
+
+// expireLoop 定期删除过期的实例。
+func (r *Registry) expireLoop() {
+	interval := max(r.ttl/4, time.Millisecond)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.closeCh:
+			return
+		case <-ticker.C:
+			r.expire()
+		}
+	}
+}
+
+func (r *Registry) expire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for serviceName, instances := range r.services {
+		for addr, e := range instances {
+			if !e.expireAt.IsZero() && !now.Before(e.expireAt) {
+				r.remove(serviceName, addr)
+			}
+		}
+	}
+}
+
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.closeCh)
+
+	for _, subs := range r.subs {
+		for _, sub := range subs {
+			sub.close()
+		}
+	}
+	r.subs = nil
+	return nil
+}
+
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		services: make(map[string]map[string]*entry, 8),
+		subs:     make(map[string][]*subscriber, 8),
+		now:      time.Now,
+		closeCh:  make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.ttl > 0 {
+		go r.expireLoop()
+	}
+	return r
+}
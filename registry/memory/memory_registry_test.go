@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testService = "user-service"
+
+func instance(addr string) registry.ServiceInstance {
+	return registry.ServiceInstance{Name: testService, Addr: addr}
+}
+
+func recv(t *testing.T, ch <-chan registry.Event) registry.Event {
+	t.Helper()
+
+	select {
+	case event, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return event
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+		return registry.Event{}
+	}
+}
+
+func noEvent(t *testing.T, ch <-chan registry.Event, wait time.Duration) {
+	t.Helper()
+
+	select {
+	case event := <-ch:
+		require.FailNow(t, "unexpected event", "%+v", event)
+	case <-time.After(wait):
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	require.NoError(t, r.Register(ctx, registry.ServiceInstance{Name: "order-service", Addr: "127.0.0.1:9091"}))
+
+	instances, err := r.ListServices(ctx, testService)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8081"), instance("127.0.0.1:8082")}, instances)
+
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8081")))
+	// 注销不存在的实例
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8083")))
+
+	instances, err = r.ListServices(ctx, testService)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8082")}, instances)
+
+	instances, err = r.ListServices(ctx, "unknown-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestRegistrySubscribe(t *testing.T) {
+	r := NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+
+	ch := r.Subscribe(ctx, testService)
+	assert.Equal(t, registry.Event{
+		Type:      registry.EventTypeSnapshot,
+		Instances: []registry.ServiceInstance{instance("127.0.0.1:8081")},
+	}, recv(t, ch))
+
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+	// 其他服务的事件不会推送
+	require.NoError(t, r.Register(ctx, registry.ServiceInstance{Name: "order-service", Addr: "127.0.0.1:9091"}))
+	require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8081")))
+
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance("127.0.0.1:8082")}, recv(t, ch))
+	assert.Equal(t, registry.Event{Type: registry.EventTypeDel, ServiceInstance: instance("127.0.0.1:8081")}, recv(t, ch))
+
+	r.Snapshot(testService)
+	assert.Equal(t, registry.Event{
+		Type:      registry.EventTypeSnapshot,
+		Instances: []registry.ServiceInstance{instance("127.0.0.1:8082")},
+	}, recv(t, ch))
+
+	require.NoError(t, r.Close())
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	_, ok = <-r.Subscribe(ctx, testService)
+	assert.False(t, ok)
+	assert.ErrorIs(t, r.Register(ctx, instance("127.0.0.1:8083")), ErrRegistryClosed)
+}
+
+func TestRegistryUpdateStatus(t *testing.T) {
+	r := NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+
+	ch := r.Subscribe(ctx, testService)
+	assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch).Type)
+
+	require.NoError(t, r.UpdateStatus(ctx, instance("127.0.0.1:8081"), registry.StatusDraining))
+
+	want := instance("127.0.0.1:8081")
+	want.Status = registry.StatusDraining
+	assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: want}, recv(t, ch))
+
+	instances, err := r.ListServices(ctx, testService)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{want}, instances)
+
+	assert.Error(t, r.UpdateStatus(ctx, instance("127.0.0.1:8082"), registry.StatusUnhealthy))
+}
+
+func TestRegistryTTL(t *testing.T) {
+	r := NewRegistry(WithTTL(100 * time.Millisecond))
+	defer func() { _ = r.Close() }()
+
+	ctx := context.Background()
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+	require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+
+	ch := r.Subscribe(ctx, testService)
+	assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch).Type)
+
+	// 持续续约的实例不会过期
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = r.Renew(instance("127.0.0.1:8082"))
+			}
+		}
+	}()
+
+	assert.Equal(t, registry.Event{Type: registry.EventTypeDel, ServiceInstance: instance("127.0.0.1:8081")}, recv(t, ch))
+	noEvent(t, ch, 200*time.Millisecond)
+
+	instances, err := r.ListServices(ctx, testService)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{instance("127.0.0.1:8082")}, instances)
+
+	assert.Error(t, r.Renew(instance("127.0.0.1:8081")))
+
+	r.Expire(instance("127.0.0.1:8082"))
+	assert.Equal(t, registry.Event{Type: registry.EventTypeDel, ServiceInstance: instance("127.0.0.1:8082")}, recv(t, ch))
+}
+
+func TestRegistryFault(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		r := NewRegistry(WithFault(Fault{
+			Drop: func(_ string, event registry.Event) bool {
+				return event.Type == registry.EventTypePut
+			},
+		}))
+		defer func() { _ = r.Close() }()
+
+		ctx := context.Background()
+		ch := r.Subscribe(ctx, testService)
+		assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch).Type)
+
+		require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+		noEvent(t, ch, 50*time.Millisecond)
+
+		require.NoError(t, r.Unregister(ctx, instance("127.0.0.1:8081")))
+		assert.Equal(t, registry.EventTypeDel, recv(t, ch).Type)
+
+		// 关闭故障注入后恢复推送
+		r.SetFault(Fault{})
+		require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+		assert.Equal(t, registry.Event{Type: registry.EventTypePut, ServiceInstance: instance("127.0.0.1:8082")}, recv(t, ch))
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		r := NewRegistry()
+		defer func() { _ = r.Close() }()
+
+		ctx := context.Background()
+		ch := r.Subscribe(ctx, testService)
+		assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch).Type)
+
+		r.SetFault(Fault{Delay: 100 * time.Millisecond})
+
+		start := time.Now()
+		require.NoError(t, r.Register(ctx, instance("127.0.0.1:8081")))
+		require.NoError(t, r.Register(ctx, instance("127.0.0.1:8082")))
+
+		// 延迟推送并且保持顺序
+		assert.Equal(t, instance("127.0.0.1:8081"), recv(t, ch).ServiceInstance)
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+		assert.Equal(t, instance("127.0.0.1:8082"), recv(t, ch).ServiceInstance)
+	})
+}
+
+func TestRegistryUnsubscribe(t *testing.T) {
+	r := NewRegistry()
+	defer func() { _ = r.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := r.Subscribe(ctx, testService)
+	assert.Equal(t, registry.EventTypeSnapshot, recv(t, ch).Type)
+
+	// ctx 取消后关闭 channel，并移除订阅者
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	require.NoError(t, r.Register(context.Background(), instance("127.0.0.1:8081")))
+	r.mu.Lock()
+	assert.Empty(t, r.subs[testService])
+	r.mu.Unlock()
+}
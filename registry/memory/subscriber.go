@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+)
+
+// subscriber 订阅者，事件先放入无界队列再由单独的协程按顺序推送，
+// 订阅者消费缓慢时不会阻塞注册中心。
+type subscriber struct {
+	ch chan registry.Event
+
+	mu     sync.Mutex
+	queue  []pending
+	notify chan struct{}
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+type pending struct {
+	event registry.Event
+	// 事件可以推送的时间，用于模拟延迟
+	at time.Time
+}
+
+func newSubscriber() *subscriber {
+	return &subscriber{
+		ch:      make(chan registry.Event),
+		notify:  make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+}
+
+func (s *subscriber) push(event registry.Event, at time.Time) {
+	s.mu.Lock()
+	s.queue = append(s.queue, pending{event: event, at: at})
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) pop() (pending, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return pending{}, false
+	}
+	p := s.queue[0]
+	s.queue = s.queue[1:]
+	return p, true
+}
+
+func (s *subscriber) run() {
+	defer close(s.ch)
+
+	for {
+		p, ok := s.pop()
+		if !ok {
+			select {
+			case <-s.closeCh:
+				return
+			case <-s.notify:
+				continue
+			}
+		}
+
+		if d := time.Until(p.at); d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-s.closeCh:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-s.closeCh:
+			return
+		case s.ch <- p.event:
+		}
+	}
+}
+
+func (s *subscriber) close() {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+}
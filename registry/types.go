@@ -2,21 +2,91 @@ package registry
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 )
 
 type Registry interface {
 	Register(ctx context.Context, instance ServiceInstance) error
 	Unregister(ctx context.Context, instance ServiceInstance) error
+	// UpdateStatus 更新已注册实例（按 Name 和 Addr 确定）的状态，订阅者会收到 put 事件。
+	UpdateStatus(ctx context.Context, instance ServiceInstance, status Status) error
 	ListServices(ctx context.Context, serviceName string) ([]ServiceInstance, error)
-	Subscribe(serviceName string) <-chan Event
+	// Subscribe 订阅服务实例变更，ctx 取消或者注册中心关闭时关闭返回的 channel。
+	Subscribe(ctx context.Context, serviceName string) <-chan Event
 	io.Closer
 }
 
+// Status 服务实例的状态，客户端只会向 StatusServing 的实例发送请求。
+type Status uint8
+
+const (
+	// StatusServing 正常提供服务，零值，未设置状态的实例都是 serving
+	StatusServing Status = iota
+	// StatusDraining 即将下线，不再接收新的请求，进行中的请求仍然会正常完成
+	StatusDraining
+	// StatusUnhealthy 健康检查失败，实例保留在注册中心但不再接收请求
+	StatusUnhealthy
+)
+
+var statusNames = map[Status]string{
+	StatusServing:   "serving",
+	StatusDraining:  "draining",
+	StatusUnhealthy: "unhealthy",
+}
+
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("status(%d)", uint8(s))
+}
+
+// MarshalText 序列化为状态名，注册中心中存储的是可读的状态名。
+func (s Status) MarshalText() ([]byte, error) {
+	if _, ok := statusNames[s]; !ok {
+		return nil, fmt.Errorf("[easy-rpc] unknown instance status %d", uint8(s))
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *Status) UnmarshalText(text []byte) error {
+	// 兼容没有 status 字段的实例
+	if len(text) == 0 {
+		*s = StatusServing
+		return nil
+	}
+	for status, name := range statusNames {
+		if name == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("[easy-rpc] unknown instance status %q", text)
+}
+
+// DefaultGroup 默认分组，Group 为空的服务实例也属于默认分组
+const DefaultGroup = "default"
+
 type ServiceInstance struct {
 	Name  string
 	Addr  string
 	Group string
+
+	// 权重，加权负载均衡使用，为 0 时按 1 处理
+	Weight uint32
+	// 协议版本，例如 "2" 或者 "1.3.0"
+	Version string
+	// 所在的可用区与地域
+	Zone   string
+	Region string
+	// 实例启动时间
+	StartTime time.Time
+	// 自定义标签
+	Labels map[string]string
+	// 实例状态
+	Status Status
 }
 
 type EventType uint8
@@ -26,9 +96,18 @@ const (
 	EventTypeUnknow EventType = iota
 	EventTypePut
 	EventTypeDel
+	// EventTypeSnapshot 全量快照，Instances 为服务当前的全部实例
+	EventTypeSnapshot
 )
 
+// Event 服务实例变更事件。
+//
+// 订阅后首先会收到一个全量快照事件，之后是增量的 put/del 事件；
+// 注册中心无法保证增量事件的连续性时（例如监听的 revision 已被压缩），会重新推送全量快照。
 type Event struct {
-	Type            EventType
+	Type EventType
+	// put/del 事件对应的服务实例
 	ServiceInstance ServiceInstance
+	// 快照事件对应的全部服务实例
+	Instances []ServiceInstance
 }
@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusJSON(t *testing.T) {
+	tcs := []struct {
+		name    string
+		val     string
+		wantErr bool
+		want    Status
+	}{
+		{name: "serving", val: `{"Addr":"127.0.0.1:8081","Status":"serving"}`, want: StatusServing},
+		{name: "draining", val: `{"Addr":"127.0.0.1:8081","Status":"draining"}`, want: StatusDraining},
+		{name: "unhealthy", val: `{"Addr":"127.0.0.1:8081","Status":"unhealthy"}`, want: StatusUnhealthy},
+		{name: "without status", val: `{"Addr":"127.0.0.1:8081"}`, want: StatusServing},
+		{name: "unknown status", val: `{"Addr":"127.0.0.1:8081","Status":"stopped"}`, wantErr: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var instance ServiceInstance
+			err := json.Unmarshal([]byte(tc.val), &instance)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, instance.Status)
+
+			val, err := json.Marshal(instance)
+			require.NoError(t, err)
+			assert.Contains(t, string(val), `"Status":"`+tc.want.String()+`"`)
+		})
+	}
+}
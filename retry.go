@@ -0,0 +1,87 @@
+package easyrpc
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryPolicy 重试策略。
+//
+// 只有幂等方法才会被重试（见 ClientBuilder.Idempotent 与 ContextWithIdempotent），
+// oneway 调用永远不会被重试。
+type RetryPolicy struct {
+	// 最大尝试次数（包含首次调用），小于等于 1 时不重试
+	MaxAttempts int
+
+	// 首次重试前的退避时间
+	InitialBackoff time.Duration
+	// 退避时间上限
+	MaxBackoff time.Duration
+	// 退避时间增长倍数，小于 1 时按 1 处理
+	Multiplier float64
+	// 随机抖动比例，取值 [0, 1]，实际退避时间在 backoff * (1 ± Jitter) 之间
+	Jitter float64
+
+	// 允许重试的状态码
+	// 客户端本地的网络错误（获取连接失败、读写失败）按 CodeUnavailable 处理
+	RetryableCodes []Code
+}
+
+// DefaultRetryPolicy 默认重试策略，最多尝试 3 次，仅重试 CodeUnavailable。
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableCodes: []Code{CodeUnavailable},
+	}
+}
+
+func (p RetryPolicy) retryable(code Code) bool {
+	return slices.Contains(p.RetryableCodes, code)
+}
+
+// backoff 计算第 attempt 次重试前的退避时间，attempt 从 1 开始。
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := math.Max(p.Multiplier, 1)
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 {
+		backoff = math.Min(backoff, float64(p.MaxBackoff))
+	}
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		backoff *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(backoff)
+}
+
+// wait 在重试前退避等待。
+// 如果剩余的超时时间不足以完成退避，直接返回 false 放弃重试。
+func (p RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	backoff := p.backoff(attempt)
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) <= backoff {
+		return false
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// methodKey 用于按 service/method 索引客户端配置。
+func methodKey(service, method string) string {
+	return service + "." + method
+}
@@ -2,26 +2,77 @@ package easyrpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/JrMarcco/easy-rpc/compress"
 	"github.com/JrMarcco/easy-rpc/compress/gzip"
 	"github.com/JrMarcco/easy-rpc/message"
+	"github.com/JrMarcco/easy-rpc/overload"
+	"github.com/JrMarcco/easy-rpc/registry"
 	"github.com/JrMarcco/easy-rpc/serialize"
 	"github.com/JrMarcco/easy-rpc/serialize/json"
 	"github.com/JrMarcco/easy-rpc/serialize/proto"
 )
 
+var ErrServerClosed = errors.New("[easy-rpc] server closed")
+
 var _ Proxy = (*Server)(nil)
 
 type Server struct {
+	mu          sync.RWMutex
 	services    map[string]*ProxyStub
 	compressors map[uint8]compress.Compressor
 	serializers map[uint8]serialize.Serializer
+
+	rateLimitRules []RateLimitRule
+	limiter        overload.Limiter
+
+	ln       net.Listener
+	conns    map[net.Conn]struct{}
+	inflight sync.WaitGroup
+	closed   bool
+
+	// 服务自动注册
+	// regMu 串行化注册中心操作并保护 addr、instances、parked 和 regClosed，
+	// 访问注册中心期间不持有 mu，避免注册中心响应慢时阻塞请求处理；需要同时持有时先获取 regMu
+	regMu         sync.Mutex
+	regClosed     bool
+	registry      registry.Registry
+	advertiseAddr string
+	drainDelay    time.Duration
+	instanceTpl   registry.ServiceInstance
+	addr          string
+	startTime     time.Time
+	instances     []registry.ServiceInstance
+
+	// 内置的健康检查服务以及是否开启反射服务
+	health     *healthService
+	reflection bool
+	watchdog   WatchdogMode
+	// 因为不健康而注销的实例，只在 WatchdogDeregister 模式下使用
+	parked []registry.ServiceInstance
+}
+
+type ServerOption func(*Server)
+
+// WithRateLimit 设置限流规则。
+func WithRateLimit(rules ...RateLimitRule) ServerOption {
+	return func(s *Server) {
+		s.rateLimitRules = append(s.rateLimitRules, rules...)
+	}
+}
+
+// WithConcurrencyLimiter 设置并发限制器，超出并发上限的请求返回 CodeOverloaded。
+func WithConcurrencyLimiter(limiter overload.Limiter) ServerOption {
+	return func(s *Server) {
+		s.limiter = limiter
+	}
 }
 
 func (s *Server) Start(addr string) error {
@@ -30,22 +81,193 @@ func (s *Server) Start(addr string) error {
 		return err
 	}
 
+	if err = s.listen(ln); err != nil {
+		_ = ln.Close()
+		return err
+	}
+
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
 			return err
 		}
 
+		if !s.trackConn(conn) {
+			_ = conn.Close()
+			continue
+		}
 		go s.handleConn(conn)
 	}
 }
 
+// listen 记录监听器，并向注册中心注册已有的服务。
+func (s *Server) listen(ln net.Listener) error {
+	s.regMu.Lock()
+	defer s.regMu.Unlock()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.ln = ln
+	s.startTime = time.Now()
+	services := make([]Service, 0, len(s.services))
+	for _, ps := range s.services {
+		services = append(services, ps.service)
+	}
+	s.mu.Unlock()
+
+	if s.registry == nil {
+		return nil
+	}
+
+	addr, err := s.resolveAdvertiseAddr(ln)
+	if err != nil {
+		return fmt.Errorf("[easy-rpc] failed to resolve advertise address: %w", err)
+	}
+	s.addr = addr
+
+	ctx, cancel := registryTimeoutContext()
+	defer cancel()
+
+	for _, service := range services {
+		if err = s.register(ctx, service); err != nil {
+			_ = s.unregister(ctx, s.instances)
+			s.instances = nil
+			return fmt.Errorf("[easy-rpc] failed to register service %s: %w", service.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Shutdown 优雅退出：
+// 1、健康检查服务的所有状态变为 HealthNotServing，将服务实例标记为 draining，等待 drainDelay 让客户端感知，期间仍然正常处理请求。
+// 2、从注册中心注销服务实例，让客户端不再路由新的请求过来。
+// 3、关闭监听，不再接受新的连接，之后收到的请求返回 CodeUnavailable。
+// 4、等待进行中的请求结束，ctx 结束时不再等待。
+// 5、关闭所有连接。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.setAll(HealthNotServing)
+
+	s.regMu.Lock()
+	instances := s.instances
+	s.instances, s.parked = nil, nil
+	// 之后不再向注册中心注册实例
+	s.regClosed = true
+	s.regMu.Unlock()
+
+	var errs []error
+	if s.registry != nil && len(instances) > 0 {
+		errs = append(errs, s.drain(ctx, instances))
+		if s.drainDelay > 0 {
+			timer := time.NewTimer(s.drainDelay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+		errs = append(errs, s.unregister(ctx, instances))
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	if s.ln != nil {
+		errs = append(errs, s.ln.Close())
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// beginRequest 记录进行中的请求，服务端关闭后返回 false。
+func (s *Server) beginRequest() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// RegisterService 注册服务，服务端已经启动并开启了自动注册时，同时向注册中心注册服务实例。
+//
+// 服务结构体上可以通过空白字段的 rpc 标签为方法指定网络上的方法名，原来的方法名仍然可以调用，
+// 标签不合法时 panic：
+//
+//	_ struct{} `rpc:"method=SayHello,name=say_hello"`
 func (s *Server) RegisterService(service Service) {
+	names, err := serverMethodNames(service)
+	if err != nil {
+		panic(fmt.Sprintf("[easy-rpc] invalid rpc tag on service %s: %v", service.Name(), err))
+	}
+
+	s.mu.Lock()
+	// 保留已经通过 RegisterHandler 注册的方法
+	handlers := make(map[string]*methodHandler)
+	if old, ok := s.services[service.Name()]; ok {
+		handlers = old.handlers
+	}
+
 	s.services[service.Name()] = &ProxyStub{
 		service:     service,
-		refVal:      reflect.ValueOf(service),
+		methods:     stubMethods(service),
+		names:       names,
+		handlers:    handlers,
 		serializers: s.serializers,
 	}
+	s.health.setIfAbsent(service.Name(), HealthServing)
+	s.mu.Unlock()
+
+	// 注册失败时服务仍然可以直接通过地址调用
+	_ = s.registerService(service)
 }
 
 func (s *Server) RegisterCompressor(compressor compress.Compressor) {
@@ -57,6 +279,11 @@ func (s *Server) RegisterSerializer(serializer serialize.Serializer) {
 }
 
 func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		s.untrackConn(conn)
+		_ = conn.Close()
+	}()
+
 	for {
 		reqBs, err := ReadMsg(conn)
 		if err != nil {
@@ -65,9 +292,15 @@ func (s *Server) handleConn(conn net.Conn) {
 
 		req := message.DecodeReq(reqBs)
 
-		ctx, cancel := s.contextFromMeta(context.Background(), req.Meta)
-		resp, err := s.Call(ctx, req)
-		cancel()
+		var resp *message.Resp
+		if s.beginRequest() {
+			ctx, cancel := s.contextFromMeta(context.Background(), req.Meta)
+			resp, err = s.Call(ctx, req)
+			cancel()
+			s.inflight.Done()
+		} else {
+			err = Errorf(CodeUnavailable, "[easy-rpc] server is shutting down")
+		}
 
 		if req.Meta[metaKeyOneway] == "true" {
 			continue
@@ -75,10 +308,13 @@ func (s *Server) handleConn(conn net.Conn) {
 		if err != nil {
 			resp = &message.Resp{
 				MessageId: req.MessageId,
+				Status:    uint8(CodeOf(err)),
 				Err:       []byte(err.Error()),
 			}
 		}
 
+		// 按请求的版本选择响应格式，旧版本的客户端无法解析状态码
+		resp.Version = min(req.Version, message.VersionStatus)
 		resp.SetLength()
 		_, err = conn.Write(message.EncodeResp(resp))
 		if err != nil {
@@ -94,7 +330,7 @@ func (s *Server) contextFromMeta(parent context.Context, meta map[string]string)
 	}
 	ctx := parent
 	cancel := func() {}
-	if dl, ok := meta[metaKeyDeadline]; ok {
+	if dl, ok := meta[MetaKeyDeadline]; ok {
 		if milli, err := strconv.ParseInt(dl, 10, 64); err == nil {
 			ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(milli))
 		}
@@ -103,28 +339,85 @@ func (s *Server) contextFromMeta(parent context.Context, meta map[string]string)
 	if oneway, ok := meta[metaKeyOneway]; ok && oneway == "true" {
 		ctx = ContextWithOneway(ctx)
 	}
+
+	// 继续传递分组，保证链式调用路由到同一分组
+	if group, ok := meta[MetaKeyGroup]; ok {
+		ctx = ContextWithGroup(ctx, group)
+	}
+
+	if attempt, ok := meta[metaKeyAttempt]; ok {
+		if n, err := strconv.Atoi(attempt); err == nil {
+			ctx = context.WithValue(ctx, contextKeyAttempt{}, n)
+		}
+	}
 	return ctx, cancel
 }
 
 func (s *Server) Call(ctx context.Context, req *message.Req) (*message.Resp, error) {
+	// 限流需要在解压和反序列化请求体之前完成
+	if err := s.allow(req); err != nil {
+		return nil, err
+	}
+
+	// 过载时尽早拒绝，同时限制了 oneway 调用启动的协程数
+	done, ok := s.acquire()
+	if !ok {
+		return nil, Errorf(CodeOverloaded, "[easy-rpc] server overloaded, request for %s.%s rejected", req.Service, req.Method)
+	}
+
 	err := s.uncompressReqBody(req)
 	if err != nil {
-		return nil, fmt.Errorf("[easy-rpc] failed to uncompress request body: %w", err)
+		done()
+		return nil, Errorf(CodeInvalidArgument, "[easy-rpc] failed to uncompress request body: %v", err)
 	}
 
+	s.mu.RLock()
 	ps, ok := s.services[req.Service]
+	var mh *methodHandler
+	if ok {
+		mh = ps.handlers[req.Method]
+	}
+	s.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("[easy-rpc] service %s not found", req.Service)
+		done()
+		return nil, Errorf(CodeNotFound, "[easy-rpc] service %s not found", req.Service)
 	}
 
 	if isOneway(ctx) {
+		// oneway 调用在单独的协程中处理，同样计入进行中的请求，Shutdown 时等待处理完成
+		if !s.beginRequest() {
+			done()
+			return nil, Errorf(CodeUnavailable, "[easy-rpc] server is shutting down")
+		}
+		ctx, cancel := detachContext(ctx)
 		go func() {
-			_, _ = ps.call(ctx, req)
+			defer s.inflight.Done()
+			defer done()
+			defer cancel()
+			_, _ = ps.call(ctx, req, mh)
 		}()
 		return nil, nil
 	}
 
-	return ps.call(ctx, req)
+	defer done()
+	return ps.call(ctx, req, mh)
+}
+
+// detachContext 返回不随 ctx 取消的 context，只保留 ctx 的超时时间。
+// Call 返回后 ctx 会被取消，oneway 调用的处理不能因此中断。
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if dl, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, dl)
+	}
+	return detached, func() {}
+}
+
+func (s *Server) acquire() (func(), bool) {
+	if s.limiter == nil {
+		return func() {}, true
+	}
+	return s.limiter.Acquire()
 }
 
 // uncompressReqBody 解压请求体
@@ -142,9 +435,10 @@ func (s *Server) uncompressReqBody(req *message.Req) error {
 	return nil
 }
 
-func NewServer() *Server {
+func NewServer(opts ...ServerOption) *Server {
 	svr := &Server{
 		services:    make(map[string]*ProxyStub, 8),
+		conns:       make(map[net.Conn]struct{}),
 		compressors: make(map[uint8]compress.Compressor, 2),
 		serializers: make(map[uint8]serialize.Serializer, 2),
 	}
@@ -155,32 +449,101 @@ func NewServer() *Server {
 	svr.RegisterSerializer(&json.Serializer{})
 	svr.RegisterSerializer(&proto.Serializer{})
 
+	for _, opt := range opts {
+		opt(svr)
+	}
+
+	svr.health = newHealthService()
+	svr.RegisterService(svr.health)
+	if svr.reflection {
+		svr.RegisterService(&reflectionService{server: svr})
+	}
 	return svr
 }
 
 type ProxyStub struct {
 	service Service
-	refVal  reflect.Value
+	// 可以远程调用的方法，只通过 RegisterHandler 注册方法的服务为空
+	methods map[string]reflect.Value
+	// 通过 rpc 标签指定的方法名到 Go 方法名的映射
+	names map[string]string
+	// 通过 RegisterHandler 注册的方法，由 Server.mu 保护
+	handlers map[string]*methodHandler
 
 	serializers map[uint8]serialize.Serializer
 }
 
-func (p *ProxyStub) call(ctx context.Context, req *message.Req) (*message.Resp, error) {
+// call 调用服务的方法，mh 不为空时调用通过 RegisterHandler 注册的方法，否则通过反射调用服务结构体上的方法。
+func (p *ProxyStub) call(ctx context.Context, req *message.Req, mh *methodHandler) (resp *message.Resp, err error) {
+	// 业务方法 panic 时返回 CodeInternal，避免整个进程退出
+	defer func() {
+		if r := recover(); r != nil {
+			resp, err = nil, Errorf(CodeInternal, "[easy-rpc] panic in %s.%s: %v", req.Service, req.Method, r)
+		}
+	}()
+
 	// 获取 serializer
 	serializer, ok := p.serializers[req.Serializer]
 	if !ok {
-		return nil, fmt.Errorf("[easy-rpc] unsupported serializer of code %c", req.Serializer)
+		return nil, Errorf(CodeInvalidArgument, "[easy-rpc] unsupported serializer of code %c", req.Serializer)
 	}
 
-	// 获取调用方法
-	method := p.refVal.MethodByName(req.Method)
+	var out any
+	if mh != nil {
+		out, err = mh.handler(ctx, func(in any) error {
+			if err := serializer.Unmarshal(req.Body, in); err != nil {
+				return Errorf(CodeInvalidArgument, "[easy-rpc] failed to unmarshal request body: %v", err)
+			}
+			return nil
+		})
+	} else {
+		out, err = p.callMethod(ctx, req, serializer)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := serializer.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message.Resp{
+		MessageId: req.MessageId,
+		Body:      respBody,
+	}, nil
+}
+
+// stubMethods 获取服务中可以远程调用的方法，见 serviceMethods。
+func stubMethods(service Service) map[string]reflect.Value {
+	val := reflect.ValueOf(service)
+	methods := serviceMethods(service)
+
+	res := make(map[string]reflect.Value, len(methods))
+	for _, method := range methods {
+		res[method.Name] = val.Method(method.Index)
+	}
+	return res
+}
+
+// callMethod 通过反射调用服务结构体上的方法。
+func (p *ProxyStub) callMethod(ctx context.Context, req *message.Req, serializer serialize.Serializer) (any, error) {
+	// 获取调用方法，只有签名符合要求的方法才能被远程调用
+	name := req.Method
+	if goName, ok := p.names[name]; ok {
+		name = goName
+	}
+	method, ok := p.methods[name]
+	if !ok {
+		return nil, Errorf(CodeNotFound, "[easy-rpc] method %s.%s not found", req.Service, req.Method)
+	}
 
 	inTyp := method.Type().In(1)
 	in := reflect.New(inTyp.Elem())
 
 	err := serializer.Unmarshal(req.Body, in.Interface())
 	if err != nil {
-		return nil, err
+		return nil, Errorf(CodeInvalidArgument, "[easy-rpc] failed to unmarshal request body: %v", err)
 	}
 
 	// 实际方法调用
@@ -188,14 +551,5 @@ func (p *ProxyStub) call(ctx context.Context, req *message.Req) (*message.Resp,
 	if len(out) > 1 && !out[1].IsZero() {
 		return nil, out[1].Interface().(error)
 	}
-
-	respBody, err := serializer.Marshal(out[0].Interface())
-	if err != nil {
-		return nil, err
-	}
-
-	return &message.Resp{
-		MessageId: req.MessageId,
-		Body:      respBody,
-	}, nil
+	return out[0].Interface(), nil
 }
@@ -0,0 +1,299 @@
+package easyrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/registry"
+)
+
+// WithRegistry 开启服务自动注册。
+//
+// 监听成功后，为每个通过 RegisterService 注册的服务向注册中心注册一个实例；
+// Shutdown 时先将所有实例标记为 draining 并从注册中心注销，让客户端不再路由新的请求过来，再等待进行中的请求结束。
+func WithRegistry(r registry.Registry) ServerOption {
+	return func(s *Server) {
+		s.registry = r
+	}
+}
+
+// WithAdvertiseAddr 设置注册到注册中心的地址。
+// 不设置时使用监听地址，监听地址没有指定 IP 时使用本机第一个非回环的 IPv4 地址。
+func WithAdvertiseAddr(addr string) ServerOption {
+	return func(s *Server) {
+		s.advertiseAddr = addr
+	}
+}
+
+// WithServiceInstance 设置注册实例的模板，例如分组、权重、版本和标签，
+// 注册时 Name 和 Addr 会被替换为服务名和对外地址。
+func WithServiceInstance(tpl registry.ServiceInstance) ServerOption {
+	return func(s *Server) {
+		s.instanceTpl = tpl
+	}
+}
+
+// WithDrainDelay 设置 Shutdown 时将实例标记为 draining 之后，等待客户端感知状态变更的时间，
+// 期间仍然正常处理请求，默认不等待。
+func WithDrainDelay(delay time.Duration) ServerOption {
+	return func(s *Server) {
+		s.drainDelay = delay
+	}
+}
+
+// WatchdogMode 健康状态变化时同步注册中心的方式。
+type WatchdogMode uint8
+
+const (
+	// WatchdogDisabled 不同步，健康状态只能通过健康检查服务查询
+	WatchdogDisabled WatchdogMode = iota
+	// WatchdogMarkUnhealthy 服务不可用时将实例标记为 unhealthy，恢复后标记为 serving
+	WatchdogMarkUnhealthy
+	// WatchdogDeregister 服务不可用时注销实例，恢复后重新注册
+	WatchdogDeregister
+)
+
+// WithHealthWatchdog 开启健康状态与注册中心的同步，需要同时开启 WithRegistry。
+func WithHealthWatchdog(mode WatchdogMode) ServerOption {
+	return func(s *Server) {
+		s.watchdog = mode
+	}
+}
+
+// registerService 服务端启动之后注册的服务，向注册中心注册服务实例，服务端还没有启动时由 listen 注册。
+func (s *Server) registerService(service Service) error {
+	if s.registry == nil {
+		return nil
+	}
+
+	s.regMu.Lock()
+	defer s.regMu.Unlock()
+
+	ctx, cancel := registryTimeoutContext()
+	defer cancel()
+	return s.register(ctx, service)
+}
+
+// register 向注册中心注册服务实例，已经注册过的服务不会重复注册，调用方需要持有 s.regMu。
+func (s *Server) register(ctx context.Context, service Service) error {
+	if s.registry == nil || s.addr == "" || s.regClosed || isInternalService(service.Name()) {
+		return nil
+	}
+	if s.registered(service.Name()) || !s.hasService(service.Name()) {
+		// 已经注册过，或者等待 regMu 期间服务已经被移除
+		return nil
+	}
+
+	instance := s.instanceTpl
+	instance.Name = service.Name()
+	instance.Addr = s.addr
+	if instance.StartTime.IsZero() {
+		instance.StartTime = s.startTime
+	}
+
+	if !s.health.serving(instance.Name) {
+		switch s.watchdog {
+		case WatchdogMarkUnhealthy:
+			instance.Status = registry.StatusUnhealthy
+		case WatchdogDeregister:
+			// 恢复后再注册
+			s.parked = append(s.parked, instance)
+			return nil
+		default:
+		}
+	}
+
+	if err := s.registry.Register(ctx, instance); err != nil {
+		return err
+	}
+	s.instances = append(s.instances, instance)
+	return nil
+}
+
+// hasService 判断服务是否存在。
+func (s *Server) hasService(service string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.services[service]
+	return ok
+}
+
+// registered 判断服务是否已经注册过实例，包括因为不健康而注销的实例，调用方需要持有 s.regMu。
+func (s *Server) registered(service string) bool {
+	for _, instance := range s.instances {
+		if instance.Name == service {
+			return true
+		}
+	}
+	for _, instance := range s.parked {
+		if instance.Name == service {
+			return true
+		}
+	}
+	return false
+}
+
+// syncHealth 按照当前的健康状态同步注册中心中的实例，调用方需要持有 s.regMu。
+func (s *Server) syncHealth(ctx context.Context) error {
+	if s.registry == nil {
+		return nil
+	}
+
+	var errs []error
+	switch s.watchdog {
+	case WatchdogMarkUnhealthy:
+		for i, instance := range s.instances {
+			status := registry.StatusServing
+			if !s.health.serving(instance.Name) {
+				status = registry.StatusUnhealthy
+			}
+			if instance.Status == status {
+				continue
+			}
+			if err := s.registry.UpdateStatus(ctx, instance, status); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.instances[i].Status = status
+		}
+	case WatchdogDeregister:
+		instances := make([]registry.ServiceInstance, 0, len(s.instances)+len(s.parked))
+		parked := make([]registry.ServiceInstance, 0, len(s.parked))
+
+		for _, instance := range s.instances {
+			if !s.health.serving(instance.Name) {
+				if err := s.registry.Unregister(ctx, instance); err != nil {
+					errs = append(errs, err)
+				} else {
+					parked = append(parked, instance)
+					continue
+				}
+			}
+			instances = append(instances, instance)
+		}
+		for _, instance := range s.parked {
+			if s.health.serving(instance.Name) {
+				if err := s.registry.Register(ctx, instance); err != nil {
+					errs = append(errs, err)
+				} else {
+					instances = append(instances, instance)
+					continue
+				}
+			}
+			parked = append(parked, instance)
+		}
+
+		s.instances, s.parked = instances, parked
+	default:
+	}
+	return errors.Join(errs...)
+}
+
+// drain 将服务实例标记为 draining，客户端不再向该实例发送新的请求。
+func (s *Server) drain(ctx context.Context, instances []registry.ServiceInstance) error {
+	var errs []error
+	for _, instance := range instances {
+		if err := s.registry.UpdateStatus(ctx, instance, registry.StatusDraining); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unregister 从注册中心注销服务实例。
+func (s *Server) unregister(ctx context.Context, instances []registry.ServiceInstance) error {
+	var errs []error
+	for _, instance := range instances {
+		if err := s.registry.Unregister(ctx, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deregisterService 服务被移除后，从注册中心注销服务实例。
+func (s *Server) deregisterService(service string) error {
+	if s.registry == nil {
+		return nil
+	}
+
+	s.regMu.Lock()
+	defer s.regMu.Unlock()
+
+	// 等待 regMu 期间服务可能又被重新注册
+	if s.hasService(service) {
+		return nil
+	}
+
+	ctx, cancel := registryTimeoutContext()
+	defer cancel()
+	return s.deregister(ctx, service)
+}
+
+// deregister 注销服务的实例，服务被移除时使用，调用方需要持有 s.regMu。
+func (s *Server) deregister(ctx context.Context, service string) error {
+	if s.registry == nil {
+		return nil
+	}
+
+	var errs []error
+	instances := s.instances[:0]
+	for _, instance := range s.instances {
+		if instance.Name != service {
+			instances = append(instances, instance)
+			continue
+		}
+		if err := s.registry.Unregister(ctx, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.instances = instances
+
+	// 已经注销的实例不需要再恢复
+	parked := s.parked[:0]
+	for _, instance := range s.parked {
+		if instance.Name != service {
+			parked = append(parked, instance)
+		}
+	}
+	s.parked = parked
+	return errors.Join(errs...)
+}
+
+// resolveAdvertiseAddr 获取注册到注册中心的地址。
+func (s *Server) resolveAdvertiseAddr(ln net.Listener) (string, error) {
+	if s.advertiseAddr != "" {
+		return s.advertiseAddr, nil
+	}
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		return ln.Addr().String(), nil
+	}
+	return net.JoinHostPort(localIP(), port), nil
+}
+
+// localIP 获取本机第一个非回环的 IPv4 地址。
+func localIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
+
+// registryTimeoutContext 注册中心操作使用的 context。
+func registryTimeoutContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), registryTimeout)
+}
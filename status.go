@@ -0,0 +1,85 @@
+package easyrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Code rpc 调用状态码，通过 message.Resp.Status 回传给客户端。
+type Code uint8
+
+const (
+	CodeOK Code = iota
+	CodeUnknown
+	CodeCanceled
+	CodeDeadlineExceeded
+	CodeInvalidArgument
+	CodeNotFound
+	CodeUnavailable
+	CodeInternal
+	CodeResourceExhausted
+	CodeOverloaded
+)
+
+var codeNames = map[Code]string{
+	CodeOK:                "ok",
+	CodeUnknown:           "unknown",
+	CodeCanceled:          "canceled",
+	CodeDeadlineExceeded:  "deadline exceeded",
+	CodeInvalidArgument:   "invalid argument",
+	CodeNotFound:          "not found",
+	CodeUnavailable:       "unavailable",
+	CodeInternal:          "internal",
+	CodeResourceExhausted: "resource exhausted",
+	CodeOverloaded:        "overloaded",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("code(%d)", uint8(c))
+}
+
+// Error 携带状态码的 rpc 错误。
+//
+// 服务端方法返回 *Error 时，状态码会原样回传给客户端；
+// 客户端收到服务端回传的错误时，也会还原成 *Error。
+type Error struct {
+	Code Code
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func NewError(code Code, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+func Errorf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// CodeOf 获取 err 对应的状态码。
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	default:
+		return CodeUnknown
+	}
+}
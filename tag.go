@@ -0,0 +1,154 @@
+package easyrpc
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JrMarcco/easy-rpc/compress"
+	"github.com/JrMarcco/easy-rpc/compress/gzip"
+	"github.com/JrMarcco/easy-rpc/serialize"
+	"github.com/JrMarcco/easy-rpc/serialize/json"
+	"github.com/JrMarcco/easy-rpc/serialize/proto"
+)
+
+// tagKey 方法配置使用的结构体标签，格式为逗号分隔的 key=value 或者 key，例如：
+//
+//	rpc:"name=say_hello,timeout=200ms,oneway,compressor=gzip,retries=3"
+const tagKey = "rpc"
+
+var tagCompressors = map[string]func() compress.Compressor{
+	"none": func() compress.Compressor { return &compress.DoNothing{} },
+	"gzip": func() compress.Compressor { return &gzip.Compressor{} },
+}
+
+var tagSerializers = map[string]func() serialize.Serializer{
+	"json":  func() serialize.Serializer { return &json.Serializer{} },
+	"proto": func() serialize.Serializer { return &proto.Serializer{} },
+}
+
+// parseTag 解析 rpc 标签，keys 为允许出现的 key。
+func parseTag(tag string, keys ...string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, val, _ := strings.Cut(part, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !slices.Contains(keys, key) {
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+		if _, ok := res[key]; ok {
+			return nil, fmt.Errorf("duplicate option %q", key)
+		}
+		res[key] = val
+	}
+	return res, nil
+}
+
+// clientMethodTag 解析客户端服务结构体字段上的标签，返回方法在网络上的名字以及调用选项。
+//
+// 支持的选项：
+//   - name：方法名，默认为字段名
+//   - timeout：超时时间，例如 200ms
+//   - oneway：oneway 调用
+//   - compressor：请求体的压缩算法，none 或者 gzip
+//   - serializer：序列化协议，json 或者 proto
+//   - retries：失败后的重试次数，见 WithCallRetryPolicy，其余参数与 DefaultRetryPolicy 相同
+func clientMethodTag(fd reflect.StructField) (string, []CallOption, error) {
+	tag, err := parseTag(fd.Tag.Get(tagKey), "name", "timeout", "oneway", "compressor", "serializer", "retries")
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := fd.Name
+	var opts []CallOption
+	for key, val := range tag {
+		switch key {
+		case "name":
+			if val == "" {
+				return "", nil, fmt.Errorf("empty name")
+			}
+			name = val
+		case "timeout":
+			timeout, err := time.ParseDuration(val)
+			if err != nil || timeout <= 0 {
+				return "", nil, fmt.Errorf("invalid timeout %q", val)
+			}
+			opts = append(opts, WithCallTimeout(timeout))
+		case "oneway":
+			if val != "" {
+				return "", nil, fmt.Errorf("oneway does not take a value")
+			}
+			opts = append(opts, WithCallOneway())
+		case "compressor":
+			fn, ok := tagCompressors[val]
+			if !ok {
+				return "", nil, fmt.Errorf("unknown compressor %q", val)
+			}
+			opts = append(opts, WithCallCompressor(fn()))
+		case "serializer":
+			fn, ok := tagSerializers[val]
+			if !ok {
+				return "", nil, fmt.Errorf("unknown serializer %q", val)
+			}
+			opts = append(opts, WithCallSerializer(fn()))
+		case "retries":
+			retries, err := strconv.Atoi(val)
+			if err != nil || retries < 0 {
+				return "", nil, fmt.Errorf("invalid retries %q", val)
+			}
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = retries + 1
+			opts = append(opts, WithCallRetryPolicy(policy))
+		}
+	}
+	return name, opts, nil
+}
+
+// serverMethodNames 解析服务结构体上空白字段的标签，返回网络上的方法名到 Go 方法名的映射，例如：
+//
+//	type UserService struct {
+//		_ struct{} `rpc:"method=SayHello,name=say_hello"`
+//	}
+func serverMethodNames(service Service) (map[string]string, error) {
+	typ := reflect.TypeOf(service)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	names := make(map[string]string)
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		raw, ok := fd.Tag.Lookup(tagKey)
+		if fd.Name != "_" || !ok {
+			continue
+		}
+
+		tag, err := parseTag(raw, "method", "name")
+		if err != nil {
+			return nil, err
+		}
+		method, name := tag["method"], tag["name"]
+		if method == "" || name == "" {
+			return nil, fmt.Errorf("both method and name are required in %q", raw)
+		}
+		if !slices.ContainsFunc(serviceMethods(service), func(m reflect.Method) bool { return m.Name == method }) {
+			return nil, fmt.Errorf("method %s not found or not callable", method)
+		}
+		if _, ok = names[name]; ok {
+			return nil, fmt.Errorf("duplicate name %q", name)
+		}
+		names[name] = method
+	}
+	return names, nil
+}
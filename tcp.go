@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+
+	"github.com/JrMarcco/easy-rpc/message"
 )
 
 const lenBytes = 8
@@ -16,7 +18,7 @@ func ReadMsg(conn net.Conn) (bs []byte, err error) {
 	}
 
 	// 读取长度字段
-	headLen := binary.BigEndian.Uint32(lenBs[:4])
+	headLen := binary.BigEndian.Uint32(lenBs[:4]) & message.HeadLenMask
 	bodyLen := binary.BigEndian.Uint32(lenBs[4:])
 	length := headLen + bodyLen
 
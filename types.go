@@ -9,8 +9,18 @@ import (
 //go:generate mockgen -source=./types.go -destination=./mock/proxy.mock.go -package=proxymock -typed Proxy
 
 const (
-	metaKeyOneway   = "oneway"
-	metaKeyDeadline = "deadline"
+	metaKeyOneway  = "oneway"
+	metaKeyAttempt = "attempt"
+)
+
+const (
+	// MetaKeyCaller 调用方标识在 meta 中的 key，通过 ClientBuilder.Caller 设置。
+	MetaKeyCaller = "caller"
+	// MetaKeyGroup 路由分组在 meta 中的 key，通过 ContextWithGroup 设置。
+	MetaKeyGroup = "x-group"
+	// MetaKeyDeadline 超时时间在 meta 中的 key，值为毫秒级的 unix 时间戳，服务端据此设置处理请求的超时。
+	// 通过 Client.Call 直接发送请求时需要自行设置。
+	MetaKeyDeadline = "deadline"
 )
 
 type Service interface {